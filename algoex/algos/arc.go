@@ -0,0 +1,227 @@
+package algos
+
+import "go-ex/pkg/cachestats"
+
+// ARCCache implements the Adaptive Replacement Cache (Megiddo & Modha). Entries seen once live
+// in recent (T1), entries seen again in frequent (T2), and the keys evicted from each are
+// remembered in a ghost list (B1, B2). A miss that hits a ghost list means that side was too
+// small, so the target size of recent (p) shifts towards it. Unlike 2Q there is nothing to tune.
+// It is not safe for concurrent use.
+type ARCCache[K comparable, V any] struct {
+	capacity       int
+	target         int             // p, the size recent is aiming for
+	recent         keyedList[K, V] // T1
+	frequent       keyedList[K, V] // T2
+	recentGhosts   keyedList[K, V] // B1, keys evicted from recent
+	frequentGhosts keyedList[K, V] // B2, keys evicted from frequent
+	onEvict        func(key K, value V)
+	stats          cachestats.Counters
+}
+
+var _ Interface[string, int] = (*ARCCache[string, int])(nil)
+
+// NewARCCache creates an ARC cache holding up to capacity (at least 1) entries.
+func NewARCCache[K comparable, V any](capacity int) *ARCCache[K, V] {
+	c := &ARCCache[K, V]{capacity: max(capacity, 1)}
+	c.Purge()
+	return c
+}
+
+// OnEvict registers fn to be called with every entry evicted to respect the capacity.
+// Entries removed with Remove or Purge are not reported.
+func (c *ARCCache[K, V]) OnEvict(fn func(key K, value V)) {
+	c.onEvict = fn
+}
+
+// lookup returns the node of a cached key.
+func (c *ARCCache[K, V]) lookup(key K) (*Node[K, V], bool) {
+	if node, ok := c.frequent.nodes[key]; ok {
+		return node, true
+	}
+	node, ok := c.recent.nodes[key]
+	return node, ok
+}
+
+// evicted reports an entry dropped to make room.
+func (c *ARCCache[K, V]) evicted(key K, value V) {
+	c.stats.Evict()
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
+}
+
+// makeRoom calls replace if the cache is full.
+func (c *ARCCache[K, V]) makeRoom(hitFrequentGhost bool) {
+	if c.Len() >= c.capacity {
+		c.replace(hitFrequentGhost)
+	}
+}
+
+// demote moves the tail of a resident list onto a ghost list, dropping its value.
+func (c *ARCCache[K, V]) demote(from, to *keyedList[K, V]) {
+	node := from.pop()
+	key, value := node.key, node.value
+	node.value = *new(V)
+	to.push(node)
+	c.evicted(key, value)
+}
+
+// replace is ARC's REPLACE: it evicts from recent if recent is over its target, else from
+// frequent. hitFrequentGhost breaks the tie when recent is exactly at it.
+func (c *ARCCache[K, V]) replace(hitFrequentGhost bool) {
+	n := c.recent.length
+	if n > 0 && (n > c.target || (hitFrequentGhost && n == c.target) || c.frequent.length == 0) {
+		c.demote(&c.recent, &c.recentGhosts)
+	} else {
+		c.demote(&c.frequent, &c.frequentGhosts)
+	}
+}
+
+// Get retrieves a value. A hit in recent promotes the entry to frequent.
+func (c *ARCCache[K, V]) Get(key K) (V, bool) {
+	if node, ok := c.frequent.nodes[key]; ok {
+		c.frequent.moveToHead(node)
+		c.stats.Hit()
+		return node.value, true
+	}
+	if node, ok := c.recent.nodes[key]; ok {
+		c.recent.unlink(node)
+		c.frequent.push(node)
+		c.stats.Hit()
+		return node.value, true
+	}
+	c.stats.Miss()
+	var zero V
+	return zero, false
+}
+
+// Peek retrieves a value without marking it as recently used.
+func (c *ARCCache[K, V]) Peek(key K) (V, bool) {
+	if node, ok := c.lookup(key); ok {
+		return node.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Contains reports whether key is in the cache without marking it as recently used.
+func (c *ARCCache[K, V]) Contains(key K) bool {
+	_, ok := c.lookup(key)
+	return ok
+}
+
+// Set adds or updates an entry, adapting the target size of recent on ghost hits.
+func (c *ARCCache[K, V]) Set(key K, value V) {
+	c.stats.Set()
+	if node, ok := c.frequent.nodes[key]; ok {
+		node.value = value
+		c.frequent.moveToHead(node)
+		return
+	}
+	if node, ok := c.recent.nodes[key]; ok {
+		node.value = value
+		c.recent.unlink(node)
+		c.frequent.push(node)
+		return
+	}
+
+	// Recent was evicted too early: grow its target
+	if ghost, ok := c.recentGhosts.nodes[key]; ok {
+		delta := max(c.frequentGhosts.length/c.recentGhosts.length, 1)
+		c.target = min(c.target+delta, c.capacity)
+		c.recentGhosts.unlink(ghost)
+		c.makeRoom(false)
+		ghost.value = value
+		c.frequent.push(ghost)
+		return
+	}
+	// Frequent was evicted too early: shrink the target of recent
+	if ghost, ok := c.frequentGhosts.nodes[key]; ok {
+		delta := max(c.recentGhosts.length/c.frequentGhosts.length, 1)
+		c.target = max(c.target-delta, 0)
+		c.frequentGhosts.unlink(ghost)
+		c.makeRoom(true)
+		ghost.value = value
+		c.frequent.push(ghost)
+		return
+	}
+
+	// A brand new key. Keep recent plus its ghosts within the capacity and everything within twice it.
+	if c.recent.length+c.recentGhosts.length >= c.capacity {
+		if c.recent.length < c.capacity {
+			c.recentGhosts.pop()
+			c.makeRoom(false)
+		} else {
+			node := c.recent.pop()
+			c.evicted(node.key, node.value)
+		}
+	} else if c.Len()+c.recentGhosts.length+c.frequentGhosts.length >= c.capacity {
+		if c.Len()+c.recentGhosts.length+c.frequentGhosts.length >= 2*c.capacity {
+			c.frequentGhosts.pop()
+		}
+		c.makeRoom(false)
+	}
+	c.recent.push(&Node[K, V]{key: key, value: value})
+}
+
+// Remove deletes key from the cache and reports whether it was present.
+func (c *ARCCache[K, V]) Remove(key K) bool {
+	if ghost, ok := c.recentGhosts.nodes[key]; ok {
+		c.recentGhosts.unlink(ghost)
+	}
+	if ghost, ok := c.frequentGhosts.nodes[key]; ok {
+		c.frequentGhosts.unlink(ghost)
+	}
+	if node, ok := c.frequent.nodes[key]; ok {
+		c.frequent.unlink(node)
+	} else if node, ok := c.recent.nodes[key]; ok {
+		c.recent.unlink(node)
+	} else {
+		return false
+	}
+	c.stats.Delete()
+	return true
+}
+
+// Len returns the number of entries in the cache (ghosts don't count).
+func (c *ARCCache[K, V]) Len() int {
+	return c.recent.length + c.frequent.length
+}
+
+// Keys returns the keys of frequent from most to least recently used, then those of recent.
+func (c *ARCCache[K, V]) Keys() []K {
+	return append(c.frequent.keys(), c.recent.keys()...)
+}
+
+// Resize changes the capacity (at least 1), evicting entries that no longer fit and trimming
+// the ghost lists. It returns how many entries were evicted.
+func (c *ARCCache[K, V]) Resize(capacity int) int {
+	c.capacity = max(capacity, 1)
+	c.target = min(c.target, c.capacity)
+	evicted := 0
+	for c.Len() > c.capacity {
+		c.replace(false)
+		evicted++
+	}
+	for c.recent.length+c.recentGhosts.length > c.capacity {
+		c.recentGhosts.pop()
+	}
+	for c.Len()+c.recentGhosts.length+c.frequentGhosts.length > 2*c.capacity {
+		c.frequentGhosts.pop()
+	}
+	return evicted
+}
+
+// Purge removes every entry, forgets the ghosts and resets the adaptation.
+func (c *ARCCache[K, V]) Purge() {
+	c.target = 0
+	c.recent = newKeyedList[K, V]()
+	c.frequent = newKeyedList[K, V]()
+	c.recentGhosts = newKeyedList[K, V]()
+	c.frequentGhosts = newKeyedList[K, V]()
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters and current size
+func (c *ARCCache[K, V]) Stats() cachestats.Stats {
+	return c.stats.Snapshot(c.Len())
+}
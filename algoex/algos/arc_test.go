@@ -0,0 +1,126 @@
+package algos
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkARC verifies ARC's size invariants and that no key is on two lists
+func checkARC[K comparable, V any](t *testing.T, c *ARCCache[K, V]) {
+	t.Helper()
+	t1, t2, b1, b2 := c.recent.length, c.frequent.length, c.recentGhosts.length, c.frequentGhosts.length
+	switch {
+	case t1+t2 > c.capacity:
+		t.Fatalf("|T1|+|T2| = %d over the capacity %d", t1+t2, c.capacity)
+	case t1+b1 > c.capacity:
+		t.Fatalf("|T1|+|B1| = %d over the capacity %d", t1+b1, c.capacity)
+	case t1+t2+b1+b2 > 2*c.capacity:
+		t.Fatalf("|T1|+|T2|+|B1|+|B2| = %d over twice the capacity %d", t1+t2+b1+b2, c.capacity)
+	case c.target < 0 || c.target > c.capacity:
+		t.Fatalf("p = %d outside [0, %d]", c.target, c.capacity)
+	}
+	seen := make(map[K]bool)
+	for _, l := range []*keyedList[K, V]{&c.recent, &c.frequent, &c.recentGhosts, &c.frequentGhosts} {
+		if len(l.nodes) != l.length {
+			t.Fatalf("list has %d nodes indexed, %d linked", len(l.nodes), l.length)
+		}
+		for _, key := range l.keys() {
+			if seen[key] {
+				t.Fatalf("key %v is on two lists", key)
+			}
+			seen[key] = true
+		}
+	}
+}
+
+func TestARCAdaptsOnGhostHits(t *testing.T) {
+	c := NewARCCache[int, int](4)
+	for i := 0; i < 4; i++ {
+		c.Set(i, i)
+	}
+	c.Get(0)
+	c.Get(1) // T1 [3 2], T2 [1 0]
+	c.Set(4, 4)
+	if got := c.recentGhosts.keys(); !slices.Equal(got, []int{2}) || c.target != 0 {
+		t.Fatalf("B1 = %v, p = %d, want [2] and 0", got, c.target)
+	}
+
+	// A B1 hit means T1 was evicted too early: p grows by max(|B2|/|B1|, 1)
+	c.Set(2, 2)
+	if c.target != 1 || !slices.Contains(c.frequent.keys(), 2) {
+		t.Fatalf("after the B1 hit: p = %d, T2 = %v", c.target, c.frequent.keys())
+	}
+	if got := c.recentGhosts.keys(); !slices.Equal(got, []int{3}) {
+		t.Fatalf("B1 = %v, want the T1 entry evicted to make room", got)
+	}
+	checkARC(t, c)
+
+	// T1 is at p, so the next miss evicts from T2 into B2
+	c.Set(5, 5)
+	if got := c.frequentGhosts.keys(); !slices.Equal(got, []int{0}) {
+		t.Fatalf("B2 = %v, want [0]", got)
+	}
+
+	// A B2 hit means T2 was evicted too early: p shrinks by max(|B1|/|B2|, 1)
+	c.Set(0, 0)
+	if c.target != 0 || !slices.Contains(c.frequent.keys(), 0) {
+		t.Fatalf("after the B2 hit: p = %d, T2 = %v", c.target, c.frequent.keys())
+	}
+	checkARC(t, c)
+}
+
+func TestARCGhostHitStepSize(t *testing.T) {
+	c := NewARCCache[int, int](8)
+	// Fill B2 with several ghosts and B1 with one, so a B1 hit moves p by |B2|/|B1|
+	for i := 0; i < 8; i++ {
+		c.Set(i, i)
+		c.Get(i) // Straight to T2
+	}
+	for i := 100; i < 105; i++ {
+		c.Set(i, i) // T1 is empty, so this pushes a T2 entry into B2
+		c.Get(i)
+	}
+	c.Set(200, 200)
+	c.Set(201, 201) // T1 is over p = 0, so 200 becomes the only B1 ghost
+	b1, b2 := c.recentGhosts.length, c.frequentGhosts.length
+	if b1 == 0 || b2 < 2*b1 {
+		t.Fatalf("|B1| = %d, |B2| = %d; the setup needs |B2| >= 2|B1|", b1, b2)
+	}
+	ghost := c.recentGhosts.keys()[0]
+	c.Set(ghost, ghost)
+	if want := b2 / b1; c.target != want {
+		t.Fatalf("p = %d after a B1 hit with |B1|=%d |B2|=%d, want %d", c.target, b1, b2, want)
+	}
+	checkARC(t, c)
+}
+
+func TestARCInvariantsUnderRandomOps(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	c := NewARCCache[int, int](16)
+	for step := 0; step < 50000; step++ {
+		key := r.Intn(48)
+		switch op := r.Intn(100); {
+		case op < 45:
+			c.Get(key)
+		case op < 90:
+			c.Set(key, step)
+		case op < 98:
+			c.Remove(key)
+		default:
+			c.Resize(1 + r.Intn(32))
+		}
+		checkARC(t, c)
+	}
+}
+
+func TestARCBeatsLRUOnScans(t *testing.T) {
+	for _, scanLength := range []int{60, 500} {
+		trace := ScanTrace(80, 20_000, 500, scanLength, 1)
+		lru := ReplayTrace(NewCache[int, int](100), trace)
+		arc := ReplayTrace(NewARCCache[int, int](100), trace)
+		if arc <= lru {
+			t.Fatalf("scans of %d keys: ARC hit ratio %.3f, LRU %.3f", scanLength, arc, lru)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package algos
+
+import (
+	"fmt"
+	"time"
+)
+
+// Entries set with SetWithTTL expire at their deadline. They are also kept in a min-heap by
+// deadline, so when the cache is full the expired entries are found without scanning and are
+// removed before any live entry is evicted. Expired entries are otherwise removed lazily: Get,
+// Peek and Contains never return them, and PurgeExpired removes them all at once.
+
+// expiryHeap is a binary min-heap of arena slots ordered by deadline. Each entry knows its
+// position in it, so a deadline can be changed or removed in O(log n). It is written out instead
+// of using container/heap, whose interface{} Push would allocate for every entry.
+type expiryHeap[K comparable, V any] struct {
+	slots   []int32
+	entries *[]entry[K, V] // Cache's arena
+}
+
+func (h *expiryHeap[K, V]) less(a, b int) bool {
+	entries := *h.entries
+	return entries[h.slots[a]].expires < entries[h.slots[b]].expires
+}
+
+func (h *expiryHeap[K, V]) swap(a, b int) {
+	h.slots[a], h.slots[b] = h.slots[b], h.slots[a]
+	entries := *h.entries
+	entries[h.slots[a]].index = int32(a)
+	entries[h.slots[b]].index = int32(b)
+}
+
+func (h *expiryHeap[K, V]) up(pos int) {
+	for pos > 0 {
+		parent := (pos - 1) / 2
+		if !h.less(pos, parent) {
+			break
+		}
+		h.swap(pos, parent)
+		pos = parent
+	}
+}
+
+// down reports whether the element moved
+func (h *expiryHeap[K, V]) down(pos int) bool {
+	start := pos
+	for {
+		child := 2*pos + 1
+		if child >= len(h.slots) {
+			break
+		}
+		if right := child + 1; right < len(h.slots) && h.less(right, child) {
+			child = right
+		}
+		if !h.less(child, pos) {
+			break
+		}
+		h.swap(pos, child)
+		pos = child
+	}
+	return pos > start
+}
+
+func (h *expiryHeap[K, V]) push(slot int32) {
+	(*h.entries)[slot].index = int32(len(h.slots))
+	h.slots = append(h.slots, slot)
+	h.up(len(h.slots) - 1)
+}
+
+func (h *expiryHeap[K, V]) remove(pos int) {
+	last := len(h.slots) - 1
+	if pos != last {
+		h.swap(pos, last)
+	}
+	h.slots = h.slots[:last]
+	if pos != last {
+		h.fix(pos)
+	}
+}
+
+func (h *expiryHeap[K, V]) fix(pos int) {
+	if !h.down(pos) {
+		h.up(pos)
+	}
+}
+
+// SetClock replaces time.Now as the source of the current time, for callers that drive time themselves.
+func (c *Cache[K, V]) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// SetWithTTL adds or updates an entry that expires after ttl. A ttl <= 0 means it never expires.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		c.set(key, value, 0)
+		return
+	}
+	c.set(key, value, c.now().Add(ttl).UnixNano())
+}
+
+// TTL returns how long key has left to live, and false if it isn't cached or never expires.
+func (c *Cache[K, V]) TTL(key K) (time.Duration, bool) {
+	i, ok := c.live(key)
+	if !ok || c.entries[i].expires == 0 {
+		return 0, false
+	}
+	return time.Duration(c.entries[i].expires - c.now().UnixNano()), true
+}
+
+// setExpiry changes the deadline of slot i (0 for none), keeping the heap in order.
+// This is an internal helper method.
+func (c *Cache[K, V]) setExpiry(i int32, expires int64) {
+	e := &c.entries[i]
+	switch {
+	case e.expires == 0 && expires == 0:
+	case e.expires == 0:
+		e.expires = expires
+		c.expiry.push(i)
+	case expires == 0:
+		c.expiry.remove(int(e.index))
+		e.expires = 0
+	default:
+		e.expires = expires
+		c.expiry.fix(int(e.index))
+	}
+}
+
+// live returns the slot of key unless it has expired, in which case it is removed.
+// This is an internal helper method.
+func (c *Cache[K, V]) live(key K) (int32, bool) {
+	i, ok := c.data[key]
+	if !ok {
+		return nilIndex, false
+	}
+	if expires := c.entries[i].expires; expires != 0 && c.now().UnixNano() >= expires {
+		c.drop(i)
+		c.stats.Expire()
+		return nilIndex, false
+	}
+	return i, true
+}
+
+// PurgeExpired removes every entry whose deadline is at or before now and returns how many
+// were removed. It only looks at the expired entries, however many live ones there are.
+func (c *Cache[K, V]) PurgeExpired(now time.Time) int {
+	deadline := now.UnixNano()
+	removed := 0
+	for len(c.expiry.slots) > 0 && c.entries[c.expiry.slots[0]].expires <= deadline {
+		c.drop(c.expiry.slots[0])
+		c.stats.Expire()
+		removed++
+	}
+	return removed
+}
+
+// purgeExpired is PurgeExpired at the current time, skipping the clock when nothing has a TTL.
+// This is an internal helper method.
+func (c *Cache[K, V]) purgeExpired() {
+	if len(c.expiry.slots) > 0 {
+		c.PurgeExpired(c.now())
+	}
+}
+
+func RunLRUCacheTTL() {
+	// A clock the demo moves by hand
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	lru := NewCache[string, string](3)
+	lru.SetClock(func() time.Time { return now })
+	lru.OnEvict(func(key, value string) {
+		fmt.Printf("  (evicted %s)\n", key)
+	})
+
+	lru.Set("config", "never expires")
+	lru.SetWithTTL("session", "expires in 1m", time.Minute)
+	lru.SetWithTTL("token", "expires in 10s", 10*time.Second)
+	ttl, _ := lru.TTL("token")
+	fmt.Printf("TTL('token') = %s\n", ttl)
+
+	now = now.Add(15 * time.Second)
+	_, ok := lru.Get("token")
+	fmt.Printf("After 15s, Get('token') found? %t\n", ok)
+
+	// The cache is full again; the expired entry goes first, although 'config' is least recently used
+	lru.SetWithTTL("token", "refreshed", 10*time.Second)
+	now = now.Add(20 * time.Second)
+	fmt.Println("Adding 'report' to a full cache with an expired 'token':")
+	lru.Set("report", "fresh")
+	fmt.Printf("Keys: %v\n", lru.Keys())
+
+	now = now.Add(time.Minute)
+	fmt.Printf("A minute later, PurgeExpired removed %d, keys: %v\n", lru.PurgeExpired(now), lru.Keys())
+	fmt.Printf("Stats: %s\n", lru.Stats())
+}
@@ -0,0 +1,205 @@
+package algos
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testClock is a clock the tests move by hand
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time          { return c.now }
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newClockedCache returns a cache driven by a testClock
+func newClockedCache(capacity int) (*Cache[string, int], *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := NewCache[string, int](capacity)
+	c.SetClock(clock.Now)
+	return c, clock
+}
+
+// checkExpiryHeap verifies that the heap holds exactly the entries with a deadline, that every
+// entry knows its position in it, and that no deadline is earlier than its parent's
+func checkExpiryHeap[K comparable, V any](t *testing.T, c *Cache[K, V]) {
+	t.Helper()
+	withTTL := 0
+	for i := c.head; i != nilIndex; i = c.entries[i].next {
+		if c.entries[i].expires != 0 {
+			withTTL++
+		}
+	}
+	if len(c.expiry.slots) != withTTL {
+		t.Fatalf("heap has %d slots, %d entries have a TTL", len(c.expiry.slots), withTTL)
+	}
+	for pos, slot := range c.expiry.slots {
+		e := c.entries[slot]
+		switch {
+		case int(e.index) != pos:
+			t.Fatalf("slot %d (key %v) is at heap position %d but records %d", slot, e.key, pos, e.index)
+		case c.data[e.key] != slot:
+			t.Fatalf("heap position %d holds slot %d, which is not the live slot of %v", pos, slot, e.key)
+		case e.expires == 0:
+			t.Fatalf("key %v is in the heap without a deadline", e.key)
+		case pos > 0 && c.entries[c.expiry.slots[(pos-1)/2]].expires > e.expires:
+			t.Fatalf("key %v expires before its parent in the heap", e.key)
+		}
+	}
+}
+
+func TestExpiryHeapBookkeeping(t *testing.T) {
+	c, clock := newClockedCache(8)
+	steps := []struct {
+		name string
+		do   func()
+	}{
+		{"set with ttl", func() {
+			for i, key := range []string{"a", "b", "c", "d", "e", "f"} {
+				c.SetWithTTL(key, i, time.Duration(10-i)*time.Second)
+			}
+		}},
+		{"set without ttl", func() { c.Set("g", 0) }},
+		{"shorten a ttl", func() { c.SetWithTTL("a", 0, time.Second) }},
+		{"lengthen a ttl", func() { c.SetWithTTL("f", 0, time.Minute) }},
+		{"clear a ttl by set", func() { c.Set("c", 0) }},
+		{"clear a ttl by zero ttl", func() { c.SetWithTTL("d", 0, 0) }},
+		{"give a ttl to a plain entry", func() { c.SetWithTTL("g", 0, 2*time.Second) }},
+		{"remove the root", func() { c.Remove("a") }},
+		{"remove a leaf", func() { c.Remove("f") }},
+		{"remove without ttl", func() { c.Remove("c") }},
+		{"expire on get", func() { clock.Advance(5 * time.Second); c.Get("e") }},
+		{"compact", func() { c.Resize(3) }},
+	}
+	for _, step := range steps {
+		step.do()
+		checkExpiryHeap(t, c)
+		if t.Failed() {
+			t.Fatalf("after %q", step.name)
+		}
+	}
+	if ttl, ok := c.TTL("b"); !ok || ttl != 4*time.Second {
+		t.Fatalf("TTL(b) = %v, %t, want 4s", ttl, ok)
+	}
+	if _, ok := c.TTL("d"); ok {
+		t.Fatal("d still has a TTL after SetWithTTL(d, 0, 0)")
+	}
+}
+
+func TestPurgeExpiredRemovesExactlyTheExpired(t *testing.T) {
+	c, clock := newClockedCache(100)
+	start := clock.Now()
+	r := rand.New(rand.NewSource(6))
+	deadlines := make(map[string]time.Time)
+	for i := 0; i < 100; i++ {
+		key := string(rune('A' + i))
+		if i%4 == 0 {
+			c.Set(key, i) // Never expires
+			continue
+		}
+		ttl := time.Duration(1+r.Intn(60)) * time.Second
+		c.SetWithTTL(key, i, ttl)
+		deadlines[key] = start.Add(ttl)
+	}
+
+	var evicted int
+	c.OnEvict(func(string, int) { evicted++ })
+	for tick := 0; tick <= 9; tick++ { // Up to 63s, past the longest TTL
+		now := start.Add(time.Duration(tick) * 7 * time.Second)
+		var want []string
+		for key, deadline := range deadlines {
+			if !deadline.After(now) { // A deadline at now has expired
+				want = append(want, key)
+			}
+		}
+		before := c.Len()
+		if removed := c.PurgeExpired(now); removed != len(want) {
+			t.Fatalf("PurgeExpired(+%v) = %d, want %d", now.Sub(start), removed, len(want))
+		}
+		for _, key := range want {
+			if _, ok := c.data[key]; ok {
+				t.Fatalf("%s outlived its deadline", key)
+			}
+			delete(deadlines, key)
+		}
+		if c.Len() != before-len(want) {
+			t.Fatalf("Len() = %d, want %d", c.Len(), before-len(want))
+		}
+		checkExpiryHeap(t, c)
+	}
+	if c.Len() != 25 || len(c.expiry.slots) != 0 {
+		t.Fatalf("%d entries and %d deadlines left, want the 25 without a TTL and none", c.Len(), len(c.expiry.slots))
+	}
+	if stats := c.Stats(); evicted != 0 || stats.Evictions != 0 || stats.Expirations != 75 {
+		t.Fatalf("OnEvict saw %d, stats %s; expirations are not evictions", evicted, stats)
+	}
+}
+
+func TestExpiredEntriesMakeRoomFirst(t *testing.T) {
+	c, clock := newClockedCache(3)
+	var evicted []string
+	c.OnEvict(func(key string, value int) { evicted = append(evicted, key) })
+	c.Set("oldest", 1)
+	c.SetWithTTL("short", 2, time.Second)
+	c.SetWithTTL("long", 3, time.Hour)
+
+	clock.Advance(time.Second)
+	c.Set("new", 4) // short expired, so oldest survives although it is least recently used
+	if got := c.Keys(); !slices.Equal(got, []string{"new", "long", "oldest"}) || len(evicted) != 0 {
+		t.Fatalf("Keys() = %v, evicted %v", got, evicted)
+	}
+	c.Set("newer", 5) // Nothing has expired, so the tail goes
+	if !slices.Equal(evicted, []string{"oldest"}) {
+		t.Fatalf("evicted %v, want [oldest]", evicted)
+	}
+	checkExpiryHeap(t, c)
+}
+
+func TestExpiryMatchesReference(t *testing.T) {
+	c, clock := newClockedCache(32)
+	r := rand.New(rand.NewSource(7))
+	ref := make(map[string]time.Time) // Deadline of each key with a TTL
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = string(rune('a' + i))
+	}
+	for step := 0; step < 20000; step++ {
+		key := keys[r.Intn(len(keys))]
+		switch op := r.Intn(100); {
+		case op < 40:
+			ttl := time.Duration(1+r.Intn(20)) * time.Millisecond
+			c.SetWithTTL(key, step, ttl)
+			ref[key] = clock.Now().Add(ttl)
+		case op < 55:
+			c.Set(key, step)
+			delete(ref, key)
+		case op < 75:
+			_, ok := c.Get(key)
+			if deadline, has := ref[key]; has && !deadline.After(clock.Now()) && ok {
+				t.Fatalf("step %d: Get(%s) returned an entry past its deadline", step, key)
+			}
+		case op < 85:
+			c.Remove(key)
+			delete(ref, key)
+		case op < 95:
+			clock.Advance(time.Duration(r.Intn(3)) * time.Millisecond)
+		default:
+			c.PurgeExpired(clock.Now())
+			for _, i := range c.data {
+				if e := c.entries[i]; e.expires != 0 && e.expires <= clock.Now().UnixNano() {
+					t.Fatalf("step %d: %v survived PurgeExpired", step, e.key)
+				}
+			}
+		}
+		// Every cached entry with a TTL must carry the deadline it was last given
+		for _, i := range c.data {
+			e := c.entries[i]
+			deadline, has := ref[e.key]
+			if has != (e.expires != 0) || (has && deadline.UnixNano() != e.expires) {
+				t.Fatalf("step %d: %s expires at %d, want %v", step, e.key, e.expires, deadline)
+			}
+		}
+		checkExpiryHeap(t, c)
+	}
+}
@@ -0,0 +1,123 @@
+package algos
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// A small trace-driven harness for comparing eviction policies: a trace is a sequence of
+// key accesses, replayed against a cache as "Get, and Set on a miss", like a read-through cache.
+
+// Workload is a named access trace
+type Workload struct {
+	Name  string
+	Trace []int
+}
+
+// Policy creates a cache of the given capacity to replay a trace against
+type Policy struct {
+	Name string
+	New  func(capacity int) Interface[int, int]
+}
+
+// Policies returns the eviction policies in this package
+func Policies() []Policy {
+	return []Policy{
+		{"LRU", func(capacity int) Interface[int, int] { return NewCache[int, int](capacity) }},
+		{"2Q", func(capacity int) Interface[int, int] { return NewTwoQueueCache[int, int](capacity) }},
+		{"ARC", func(capacity int) Interface[int, int] { return NewARCCache[int, int](capacity) }},
+		{"LFU", func(capacity int) Interface[int, int] { return NewLFUCache[int, int](capacity) }},
+	}
+}
+
+// HotSetTrace returns n accesses to keys 0..hot-1, Zipf-distributed so a few keys are very hot
+func HotSetTrace(hot, n int, seed int64) []int {
+	zipf := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.1, 1, uint64(hot-1))
+	trace := make([]int, n)
+	for i := range trace {
+		trace[i] = int(zipf.Uint64())
+	}
+	return trace
+}
+
+// ScanTrace is HotSetTrace with a scan of scanLength never-repeated keys after every scanEvery accesses
+func ScanTrace(hot, n, scanEvery, scanLength int, seed int64) []int {
+	trace := make([]int, 0, n+n/scanEvery*scanLength)
+	next := hot // Scanned keys come after the hot set and are never seen again
+	for i, key := range HotSetTrace(hot, n, seed) {
+		trace = append(trace, key)
+		if (i+1)%scanEvery == 0 {
+			for j := 0; j < scanLength; j++ {
+				trace = append(trace, next)
+				next++
+			}
+		}
+	}
+	return trace
+}
+
+// LoopTrace cycles through keys 0..loop-1 until there are n accesses, LRU's worst case
+// once loop exceeds the capacity
+func LoopTrace(loop, n int) []int {
+	trace := make([]int, n)
+	for i := range trace {
+		trace[i] = i % loop
+	}
+	return trace
+}
+
+// ReplayTrace runs trace against cache and returns the hit ratio
+func ReplayTrace(cache Interface[int, int], trace []int) float64 {
+	hits := 0
+	for _, key := range trace {
+		if _, ok := cache.Get(key); ok {
+			hits++
+			continue
+		}
+		cache.Set(key, key)
+	}
+	if len(trace) == 0 {
+		return 0
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+// CompareHitRatios replays every workload against a fresh cache of every policy and
+// returns the hit ratios, indexed [workload][policy]
+func CompareHitRatios(capacity int, workloads []Workload, policies []Policy) [][]float64 {
+	ratios := make([][]float64, len(workloads))
+	for i, workload := range workloads {
+		ratios[i] = make([]float64, len(policies))
+		for j, policy := range policies {
+			ratios[i][j] = ReplayTrace(policy.New(capacity), workload.Trace)
+		}
+	}
+	return ratios
+}
+
+func RunScanResistance() {
+	const capacity = 1000
+	workloads := []Workload{
+		{"hot set (800 keys)", HotSetTrace(800, 200_000, 1)},
+		{"hot set + short scans", ScanTrace(800, 200_000, 5000, 600, 1)},
+		// Scans longer than 2Q's ghost list wash out the keys it would have promoted; ARC adapts
+		{"hot set + long scans", ScanTrace(800, 200_000, 5000, 5000, 1)},
+		{"loop of 1200 keys", LoopTrace(1200, 200_000)},
+	}
+	policies := Policies()
+	ratios := CompareHitRatios(capacity, workloads, policies)
+
+	fmt.Printf("Hit ratios with a capacity of %d entries\n", capacity)
+	fmt.Printf("%-22s", "workload")
+	for _, policy := range policies {
+		fmt.Printf("%8s", policy.Name)
+	}
+	fmt.Println()
+	for i, workload := range workloads {
+		fmt.Printf("%-22s", workload.Name)
+		for j := range policies {
+			fmt.Printf("%8.3f", ratios[i][j])
+		}
+		fmt.Println()
+	}
+}
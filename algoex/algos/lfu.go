@@ -0,0 +1,312 @@
+package algos
+
+import (
+	"fmt"
+	"go-ex/pkg/cachestats"
+)
+
+// LFUCache evicts the least frequently used entry, and among those the least recently used.
+// Entries are kept in frequency buckets, each a list of its entries in recency order, and the
+// buckets form a list of their own in increasing frequency. A hit moves the entry to the next
+// bucket (creating it if needed) and the victim is always the tail of the first bucket, so
+// Get and Set are O(1).
+//
+// Plain LFU never forgets: a key that was hot yesterday keeps its rank forever. With DecayEvery
+// set, every frequency is halved after that many accesses so old popularity fades. Decaying
+// touches every entry, so keep the interval well above the capacity to stay amortized O(1).
+// It is not safe for concurrent use.
+type LFUCache[K comparable, V any] struct {
+	capacity   int
+	data       map[K]*Node[K, lfuEntry[K, V]]
+	head       *lfuBucket[K, V] // Lowest frequency
+	tail       *lfuBucket[K, V] // Highest frequency
+	decayEvery int              // Accesses between decays, 0 disables decay
+	accesses   int              // Accesses since the last decay
+	onEvict    func(key K, value V)
+	stats      cachestats.Counters
+}
+
+// lfuEntry is what LFUCache stores in its nodes
+type lfuEntry[K comparable, V any] struct {
+	value  V
+	bucket *lfuBucket[K, V]
+}
+
+// lfuBucket holds the entries used freq times, most recently used first
+type lfuBucket[K comparable, V any] struct {
+	freq    int
+	entries list[K, lfuEntry[K, V]]
+	prev    *lfuBucket[K, V]
+	next    *lfuBucket[K, V]
+}
+
+var _ Interface[string, int] = (*LFUCache[string, int])(nil)
+
+// NewLFUCache creates an LFU cache holding up to capacity (at least 1) entries.
+func NewLFUCache[K comparable, V any](capacity int) *LFUCache[K, V] {
+	return &LFUCache[K, V]{
+		capacity: max(capacity, 1),
+		data:     make(map[K]*Node[K, lfuEntry[K, V]]),
+	}
+}
+
+// OnEvict registers fn to be called with every entry evicted to respect the capacity.
+// Entries removed with Remove or Purge are not reported.
+func (c *LFUCache[K, V]) OnEvict(fn func(key K, value V)) {
+	c.onEvict = fn
+}
+
+// DecayEvery halves every frequency after each n accesses (Get hits and Sets). 0 disables decay.
+func (c *LFUCache[K, V]) DecayEvery(n int) {
+	c.decayEvery = max(n, 0)
+	c.accesses = 0
+}
+
+// insertBucket adds an empty bucket after prev, or at the head if prev is nil.
+func (c *LFUCache[K, V]) insertBucket(prev *lfuBucket[K, V], freq int) *lfuBucket[K, V] {
+	bucket := &lfuBucket[K, V]{freq: freq, prev: prev}
+	if prev != nil {
+		bucket.next = prev.next
+		prev.next = bucket
+	} else {
+		bucket.next = c.head
+		c.head = bucket
+	}
+	if bucket.next != nil {
+		bucket.next.prev = bucket
+	} else {
+		c.tail = bucket
+	}
+	return bucket
+}
+
+// removeBucket unlinks an empty bucket.
+func (c *LFUCache[K, V]) removeBucket(bucket *lfuBucket[K, V]) {
+	if bucket.prev != nil {
+		bucket.prev.next = bucket.next
+	} else {
+		c.head = bucket.next
+	}
+	if bucket.next != nil {
+		bucket.next.prev = bucket.prev
+	} else {
+		c.tail = bucket.prev
+	}
+}
+
+// unlink takes node out of its bucket, dropping the bucket once it is empty.
+func (c *LFUCache[K, V]) unlink(node *Node[K, lfuEntry[K, V]]) {
+	bucket := node.value.bucket
+	bucket.entries.removeNode(node)
+	if bucket.entries.length == 0 {
+		c.removeBucket(bucket)
+	}
+}
+
+// touch moves node to the bucket of the next frequency and counts the access.
+func (c *LFUCache[K, V]) touch(node *Node[K, lfuEntry[K, V]]) {
+	bucket := node.value.bucket
+	next := bucket.next
+	if next == nil || next.freq != bucket.freq+1 {
+		next = c.insertBucket(bucket, bucket.freq+1)
+	}
+	c.unlink(node)
+	next.entries.addNode(node)
+	node.value.bucket = next
+	c.access()
+}
+
+// access counts an access towards the next decay.
+func (c *LFUCache[K, V]) access() {
+	if c.decayEvery == 0 {
+		return
+	}
+	c.accesses++
+	if c.accesses >= c.decayEvery {
+		c.accesses = 0
+		c.decay()
+	}
+}
+
+// decay halves every frequency (to at least 1). Buckets that end up with the same frequency
+// are merged, with the entries of the formerly more frequent bucket ranked as more recent.
+func (c *LFUCache[K, V]) decay() {
+	for bucket := c.head; bucket != nil; {
+		next := bucket.next
+		freq := max(bucket.freq/2, 1)
+		if prev := bucket.prev; prev != nil && prev.freq == freq {
+			for node := bucket.entries.tail; node != nil; {
+				older := node.prev
+				bucket.entries.removeNode(node)
+				prev.entries.addNode(node)
+				node.value.bucket = prev
+				node = older
+			}
+			c.removeBucket(bucket)
+		} else {
+			bucket.freq = freq
+		}
+		bucket = next
+	}
+}
+
+// evict drops the least recently used entry of the lowest frequency.
+func (c *LFUCache[K, V]) evict() {
+	node := c.head.entries.tail
+	c.unlink(node)
+	delete(c.data, node.key)
+	c.stats.Evict()
+	if c.onEvict != nil {
+		c.onEvict(node.key, node.value.value)
+	}
+}
+
+// Get retrieves a value, counting the use.
+func (c *LFUCache[K, V]) Get(key K) (V, bool) {
+	if node, ok := c.data[key]; ok {
+		c.touch(node)
+		c.stats.Hit()
+		return node.value.value, true
+	}
+	c.stats.Miss()
+	var zero V
+	return zero, false
+}
+
+// Peek retrieves a value without counting the use.
+func (c *LFUCache[K, V]) Peek(key K) (V, bool) {
+	if node, ok := c.data[key]; ok {
+		return node.value.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Contains reports whether key is in the cache without counting the use.
+func (c *LFUCache[K, V]) Contains(key K) bool {
+	_, ok := c.data[key]
+	return ok
+}
+
+// Frequency returns how often key has been used since it was added, as adjusted by decay.
+func (c *LFUCache[K, V]) Frequency(key K) (int, bool) {
+	if node, ok := c.data[key]; ok {
+		return node.value.bucket.freq, true
+	}
+	return 0, false
+}
+
+// Set adds a new entry with a frequency of 1, or updates an existing one and counts the use.
+func (c *LFUCache[K, V]) Set(key K, value V) {
+	c.stats.Set()
+	if node, ok := c.data[key]; ok {
+		node.value.value = value
+		c.touch(node)
+		return
+	}
+
+	if len(c.data) >= c.capacity {
+		c.evict()
+	}
+	bucket := c.head
+	if bucket == nil || bucket.freq != 1 {
+		bucket = c.insertBucket(nil, 1)
+	}
+	node := &Node[K, lfuEntry[K, V]]{key: key, value: lfuEntry[K, V]{value: value, bucket: bucket}}
+	bucket.entries.addNode(node)
+	c.data[key] = node
+	c.access()
+}
+
+// Remove deletes key from the cache and reports whether it was present.
+func (c *LFUCache[K, V]) Remove(key K) bool {
+	node, ok := c.data[key]
+	if !ok {
+		return false
+	}
+	c.unlink(node)
+	delete(c.data, key)
+	c.stats.Delete()
+	return true
+}
+
+// Len returns the number of entries in the cache.
+func (c *LFUCache[K, V]) Len() int {
+	return len(c.data)
+}
+
+// Keys returns the keys from the most to the least frequently used, ties in recency order,
+// i.e. the reverse of the eviction order.
+func (c *LFUCache[K, V]) Keys() []K {
+	keys := make([]K, 0, len(c.data))
+	for bucket := c.tail; bucket != nil; bucket = bucket.prev {
+		keys = append(keys, bucket.entries.keys()...)
+	}
+	return keys
+}
+
+// Resize changes the capacity (at least 1), evicting the entries that no longer fit.
+// It returns how many entries were evicted.
+func (c *LFUCache[K, V]) Resize(capacity int) int {
+	c.capacity = max(capacity, 1)
+	evicted := 0
+	for len(c.data) > c.capacity {
+		c.evict()
+		evicted++
+	}
+	return evicted
+}
+
+// Purge removes every entry.
+func (c *LFUCache[K, V]) Purge() {
+	c.data = make(map[K]*Node[K, lfuEntry[K, V]])
+	c.head, c.tail = nil, nil
+	c.accesses = 0
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters and current size
+func (c *LFUCache[K, V]) Stats() cachestats.Stats {
+	return c.stats.Snapshot(len(c.data))
+}
+
+func RunLFUCache() {
+	lfu := NewLFUCache[string, int](3)
+	lfu.OnEvict(func(key string, value int) {
+		fmt.Printf("  (evicted %s=%d)\n", key, value)
+	})
+
+	lfu.Set("a", 1)
+	lfu.Set("b", 2)
+	lfu.Set("c", 3)
+	lfu.Get("a")
+	lfu.Get("a")
+	lfu.Get("b")
+	fmt.Printf("Keys by frequency: %v\n", lfu.Keys()) // a (3), b (2), c (1)
+
+	fmt.Println("\nAdding 'd' evicts 'c', the least frequently used, although 'a' was used longer ago.")
+	lfu.Set("d", 4)
+	fmt.Println("Adding 'e': 'd' and 'e' tie at frequency 1 and 'd' is older.")
+	lfu.Set("e", 5)
+
+	// Without decay a key that was once hot never leaves
+	fmt.Println("\n'a' becomes very popular, then is never used again.")
+	for i := 0; i < 50; i++ {
+		lfu.Get("a")
+	}
+	freq, _ := lfu.Frequency("a")
+	fmt.Printf("Frequency('a') = %d\n", freq)
+
+	// x, y and z keep pushing each other out while 'a' holds on to its rank
+	lfu.OnEvict(nil)
+	lfu.DecayEvery(20)
+	rounds := 0
+	for ; lfu.Contains("a") && rounds < 1000; rounds++ {
+		for _, key := range []string{"x", "y", "z"} {
+			if _, ok := lfu.Get(key); !ok {
+				lfu.Set(key, rounds)
+			}
+		}
+	}
+	fmt.Printf("With DecayEvery(20), 'a' was evicted after %d rounds of x, y, z; keys: %v\n", rounds, lfu.Keys())
+	fmt.Printf("\nStats: %s\n", lfu.Stats())
+}
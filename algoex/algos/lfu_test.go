@@ -0,0 +1,258 @@
+package algos
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkLFU verifies the bucket list, the entry lists inside it and the map of c
+func checkLFU[K comparable, V any](t *testing.T, c *LFUCache[K, V]) {
+	t.Helper()
+	entries := 0
+	var prev *lfuBucket[K, V]
+	for bucket := c.head; bucket != nil; bucket = bucket.next {
+		switch {
+		case bucket.prev != prev:
+			t.Fatalf("bucket %d has the wrong prev", bucket.freq)
+		case prev != nil && prev.freq >= bucket.freq:
+			t.Fatalf("bucket %d follows bucket %d", bucket.freq, prev.freq)
+		case bucket.entries.length == 0:
+			t.Fatalf("bucket %d is empty", bucket.freq)
+		}
+		linked := 0
+		var prevNode *Node[K, lfuEntry[K, V]]
+		for node := bucket.entries.head; node != nil; node = node.next {
+			if node.prev != prevNode || node.value.bucket != bucket || c.data[node.key] != node {
+				t.Fatalf("key %v is misfiled in bucket %d", node.key, bucket.freq)
+			}
+			prevNode = node
+			linked++
+		}
+		if bucket.entries.tail != prevNode || linked != bucket.entries.length {
+			t.Fatalf("bucket %d links %d entries, length %d", bucket.freq, linked, bucket.entries.length)
+		}
+		entries += linked
+		prev = bucket
+	}
+	if c.tail != prev || entries != len(c.data) || entries > c.capacity {
+		t.Fatalf("%d entries in buckets, %d mapped, capacity %d", entries, len(c.data), c.capacity)
+	}
+}
+
+func TestLFUEvictionOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		ops      string // Space separated: set:k get:k peek:k rm:k resize:n
+		keys     string // Most to least frequently used, ties most recent first
+		evicted  string // In eviction order
+	}{
+		{"evicts the lowest frequency", 2, "set:a set:b get:a set:c", "a c", "b"},
+		{"ties go to the least recently used", 2, "set:a set:b set:c", "c b", "a"},
+		{"ties among promoted keys", 2, "set:a set:b get:b get:a set:c", "a c", "b"},
+		{"recency within a bucket", 3, "set:a set:b set:c get:a get:b set:d", "b a d", "c"},
+		{"update counts as a use", 2, "set:a set:b set:a set:c", "a c", "b"},
+		{"peek does not count", 2, "set:a set:b peek:a set:c", "c b", "a"},
+		{"old favourites outrank new keys", 2, "set:a get:a get:a set:b set:c", "a c", "b"},
+		{"get of a missing key changes nothing", 2, "set:a set:b get:z set:c", "c b", "a"},
+		{"remove makes room", 2, "set:a set:b rm:a set:c", "c b", ""},
+		{"resize down evicts the least frequent first", 3, "set:a set:b set:c get:c get:c get:b resize:1", "c", "a b"},
+		{"resize up keeps entries", 2, "set:a get:a set:b resize:3 set:c", "a c b", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLFUCache[string, int](tt.capacity)
+			var evicted []string
+			c.OnEvict(func(key string, value int) { evicted = append(evicted, key) })
+			for n, op := range strings.Fields(tt.ops) {
+				name, arg, _ := strings.Cut(op, ":")
+				switch name {
+				case "set":
+					c.Set(arg, n)
+				case "get":
+					c.Get(arg)
+				case "peek":
+					c.Peek(arg)
+				case "rm":
+					c.Remove(arg)
+				case "resize":
+					size, _ := strconv.Atoi(arg)
+					c.Resize(size)
+				}
+				checkLFU(t, c)
+			}
+			if got := strings.Join(c.Keys(), " "); got != tt.keys {
+				t.Fatalf("Keys() = %q, want %q", got, tt.keys)
+			}
+			if got := strings.Join(evicted, " "); got != tt.evicted {
+				t.Fatalf("evicted %q, want %q", got, tt.evicted)
+			}
+		})
+	}
+}
+
+func TestLFUDecayHalvesFrequencies(t *testing.T) {
+	c := NewLFUCache[string, int](4)
+	use := func(key string, times int) {
+		c.Set(key, 0)
+		for i := 1; i < times; i++ {
+			c.Get(key)
+		}
+	}
+	use("a", 9)
+	use("b", 4)
+	use("c", 1)
+
+	c.DecayEvery(2)
+	c.Get("missing")
+	c.Peek("a")
+	c.Get("a") // Misses and peeks are not accesses, so this is the first of two
+	if freq, _ := c.Frequency("a"); freq != 10 {
+		t.Fatalf("Frequency(a) = %d before the decay, want 10", freq)
+	}
+	c.Get("b") // b reaches 5, then everything is halved
+	for key, want := range map[string]int{"a": 5, "b": 2, "c": 1} {
+		if freq, _ := c.Frequency(key); freq != want {
+			t.Errorf("Frequency(%s) = %d after the decay, want %d", key, freq, want)
+		}
+	}
+	checkLFU(t, c)
+	if got := strings.Join(c.Keys(), " "); got != "a b c" {
+		t.Fatalf("Keys() = %q, want the frequency order kept", got)
+	}
+
+	c.DecayEvery(0)
+	for i := 0; i < 10; i++ {
+		c.Get("c")
+	}
+	if freq, _ := c.Frequency("c"); freq != 11 {
+		t.Fatalf("Frequency(c) = %d with decay disabled, want 11", freq)
+	}
+}
+
+func TestLFUDecayMergesBuckets(t *testing.T) {
+	c := NewLFUCache[string, int](8)
+	c.Set("x", 0) // Frequency 1
+	c.Set("q", 0)
+	c.Set("p", 0)
+	c.Get("q")
+	c.Get("p") // Frequency 2: p, q
+	c.Set("z", 0)
+	c.Get("z")
+	c.Get("z") // Frequency 3
+	c.Set("w", 0)
+	for i := 0; i < 4; i++ {
+		c.Get("w") // Frequency 5
+	}
+
+	// 1 and 2 both become 1, 3 becomes 1 as well and 5 becomes 2. Entries that were used
+	// more before the decay rank as more recent in the merged bucket.
+	c.decay()
+	checkLFU(t, c)
+	if c.head.freq != 1 || c.head.next != c.tail || c.tail.freq != 2 {
+		t.Fatalf("buckets after the decay: first %d, last %d, want 1 and 2", c.head.freq, c.tail.freq)
+	}
+	if got := strings.Join(c.head.entries.keys(), " "); got != "z p q x" {
+		t.Fatalf("merged bucket = %q, want %q", got, "z p q x")
+	}
+
+	var evicted []string
+	c.OnEvict(func(key string, value int) { evicted = append(evicted, key) })
+	c.Resize(2)
+	if got := strings.Join(evicted, " "); got != "x q p" {
+		t.Fatalf("evicted %q, want the merged bucket's least recent first", got)
+	}
+}
+
+func TestLFUMatchesReference(t *testing.T) {
+	// The reference ranks by (frequency, time of last use), which is exactly LFU with LRU ties
+	type use struct{ freq, last int }
+	r := rand.New(rand.NewSource(3))
+	c := NewLFUCache[int, int](12)
+	ref := make(map[int]use)
+	capacity := 12
+	var evicted []int
+	c.OnEvict(func(key int, value int) { evicted = append(evicted, key) })
+	evictRef := func() int {
+		victim, first := 0, true
+		for key, u := range ref {
+			v := ref[victim]
+			if first || u.freq < v.freq || (u.freq == v.freq && u.last < v.last) {
+				victim, first = key, false
+			}
+		}
+		delete(ref, victim)
+		return victim
+	}
+
+	for step := 0; step < 20000; step++ {
+		key := r.Intn(30)
+		var want []int
+		switch op := r.Intn(100); {
+		case op < 45:
+			_, ok := c.Get(key)
+			if u, hit := ref[key]; hit != ok {
+				t.Fatalf("step %d: Get(%d) hit = %t, want %t", step, key, ok, hit)
+			} else if hit {
+				ref[key] = use{u.freq + 1, step}
+			}
+		case op < 90:
+			c.Set(key, step)
+			if u, ok := ref[key]; ok {
+				ref[key] = use{u.freq + 1, step}
+			} else {
+				if len(ref) >= capacity {
+					want = append(want, evictRef())
+				}
+				ref[key] = use{1, step}
+			}
+		case op < 98:
+			c.Remove(key)
+			delete(ref, key)
+		default:
+			capacity = 1 + r.Intn(24)
+			c.Resize(capacity)
+			for len(ref) > capacity {
+				want = append(want, evictRef())
+			}
+		}
+		if !slices.Equal(evicted, want) {
+			t.Fatalf("step %d: evicted %v, want %v", step, evicted, want)
+		}
+		evicted = evicted[:0]
+
+		keys := make([]int, 0, len(ref))
+		for key := range ref {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b int) int {
+			return cmp.Or(cmp.Compare(ref[b].freq, ref[a].freq), cmp.Compare(ref[b].last, ref[a].last))
+		})
+		if got := c.Keys(); !slices.Equal(got, keys) {
+			t.Fatalf("step %d: Keys() = %v, want %v", step, got, keys)
+		}
+	}
+	checkLFU(t, c)
+}
+
+func TestLFUInvariantsUnderDecay(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	c := NewLFUCache[int, int](16)
+	c.DecayEvery(37)
+	for step := 0; step < 20000; step++ {
+		key := r.Intn(40)
+		switch op := r.Intn(100); {
+		case op < 50:
+			c.Get(key)
+		case op < 95:
+			c.Set(key, step)
+		default:
+			c.Remove(key)
+		}
+		checkLFU(t, c)
+	}
+}
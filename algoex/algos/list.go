@@ -0,0 +1,102 @@
+package algos
+
+// Node represents a single element in the doubly linked list.
+type Node[K comparable, V any] struct {
+	key   K
+	value V
+	prev  *Node[K, V]
+	next  *Node[K, V]
+}
+
+// list is a doubly linked list of nodes, most recently used at the head.
+// TwoQueueCache, ARCCache and LFUCache are built out of one or more of them.
+type list[K comparable, V any] struct {
+	head   *Node[K, V]
+	tail   *Node[K, V]
+	length int
+}
+
+// addNode adds a new node to the head of the doubly linked list.
+// This is an internal helper method.
+func (c *list[K, V]) addNode(node *Node[K, V]) {
+	node.prev = nil
+	node.next = c.head
+
+	if c.head != nil {
+		c.head.prev = node
+	}
+	c.head = node
+
+	if c.tail == nil {
+		c.tail = node
+	}
+	c.length++
+}
+
+// removeNode removes a node from the doubly linked list.
+// This is an internal helper method.
+func (c *list[K, V]) removeNode(node *Node[K, V]) {
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		// Node is the head
+		c.head = node.next
+	}
+
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		// Node is the tail
+		c.tail = node.prev
+	}
+	c.length--
+}
+
+// moveToHead moves an existing node to the head of the linked list.
+// This signifies that it has been "recently used."
+// This is an internal helper method.
+func (c *list[K, V]) moveToHead(node *Node[K, V]) {
+	c.removeNode(node)
+	c.addNode(node)
+}
+
+// keys returns the keys from head to tail.
+// This is an internal helper method.
+func (c *list[K, V]) keys() []K {
+	keys := make([]K, 0, c.length)
+	for node := c.head; node != nil; node = node.next {
+		keys = append(keys, node.key)
+	}
+	return keys
+}
+
+// keyedList is a list with its own key index, so the multi-list caches know which list a key is on.
+type keyedList[K comparable, V any] struct {
+	list[K, V]
+	nodes map[K]*Node[K, V]
+}
+
+func newKeyedList[K comparable, V any]() keyedList[K, V] {
+	return keyedList[K, V]{nodes: make(map[K]*Node[K, V])}
+}
+
+// push adds node at the head.
+func (l *keyedList[K, V]) push(node *Node[K, V]) {
+	l.addNode(node)
+	l.nodes[node.key] = node
+}
+
+// unlink takes node off the list.
+func (l *keyedList[K, V]) unlink(node *Node[K, V]) {
+	l.removeNode(node)
+	delete(l.nodes, node.key)
+}
+
+// pop takes the tail off the list, or returns nil if it is empty.
+func (l *keyedList[K, V]) pop() *Node[K, V] {
+	node := l.tail
+	if node != nil {
+		l.unlink(node)
+	}
+	return node
+}
@@ -0,0 +1,180 @@
+package algos
+
+import (
+	containerlist "container/list"
+	"math/rand"
+	"testing"
+)
+
+// Compares the arena-backed Cache with the same LRU built from pointer nodes (as Cache used to
+// be) and from container/list. Once a cache is full, every Set of a new key evicts one entry:
+// the pointer versions allocate a node for it, the arena reuses the evicted entry's slot.
+// Cache also keeps stats and checks TTLs, which the two stripped-down versions don't.
+//
+//	go test -bench LRU -benchmem ./algoex/algos
+
+const lruBenchmarkCapacity = 10_000
+
+// lruBench is what the benchmarks need from a cache
+type lruBench interface {
+	Get(key int) (int, bool)
+	Set(key int, value int)
+}
+
+// pointerCache is the LRU on heap-allocated nodes that Cache was before it moved to an arena
+type pointerCache struct {
+	list[int, int]
+	capacity int
+	data     map[int]*Node[int, int]
+}
+
+func newPointerCache(capacity int) *pointerCache {
+	return &pointerCache{capacity: capacity, data: make(map[int]*Node[int, int])}
+}
+
+func (c *pointerCache) Get(key int) (int, bool) {
+	if node, ok := c.data[key]; ok {
+		c.moveToHead(node)
+		return node.value, true
+	}
+	return 0, false
+}
+
+func (c *pointerCache) Set(key int, value int) {
+	if node, ok := c.data[key]; ok {
+		node.value = value
+		c.moveToHead(node)
+		return
+	}
+	node := &Node[int, int]{key: key, value: value}
+	c.data[key] = node
+	c.addNode(node)
+	if c.length > c.capacity {
+		tail := c.tail
+		c.removeNode(tail)
+		delete(c.data, tail.key)
+	}
+}
+
+// stdListCache is the textbook LRU on container/list
+type stdListCache struct {
+	capacity int
+	order    *containerlist.List // Of *stdListEntry, most recently used first
+	data     map[int]*containerlist.Element
+}
+
+type stdListEntry struct {
+	key   int
+	value int
+}
+
+func newStdListCache(capacity int) *stdListCache {
+	return &stdListCache{capacity: capacity, order: containerlist.New(), data: make(map[int]*containerlist.Element)}
+}
+
+func (c *stdListCache) Get(key int) (int, bool) {
+	if elem, ok := c.data[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*stdListEntry).value, true
+	}
+	return 0, false
+}
+
+func (c *stdListCache) Set(key int, value int) {
+	if elem, ok := c.data[key]; ok {
+		elem.Value.(*stdListEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.data[key] = c.order.PushFront(&stdListEntry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.data, oldest.Value.(*stdListEntry).key)
+	}
+}
+
+// The rest of stdListCache lets TestCacheMatchesReferenceLRU use it as the reference model
+
+func (c *stdListCache) Remove(key int) bool {
+	elem, ok := c.data[key]
+	if ok {
+		c.order.Remove(elem)
+		delete(c.data, key)
+	}
+	return ok
+}
+
+func (c *stdListCache) Resize(capacity int) {
+	c.capacity = max(capacity, 1)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.data, oldest.Value.(*stdListEntry).key)
+	}
+}
+
+func (c *stdListCache) Keys() []int {
+	keys := make([]int, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(*stdListEntry).key)
+	}
+	return keys
+}
+
+// benchmarkLRU fills a fresh cache, then replays keys round-robin: Get, and Set on a miss
+func benchmarkLRU(b *testing.B, newCache func() lruBench, keys []int) {
+	cache := newCache()
+	for i := 0; i < lruBenchmarkCapacity; i++ {
+		cache.Set(i, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		if _, ok := cache.Get(key); !ok {
+			cache.Set(key, i)
+		}
+	}
+}
+
+// benchmarkLRUWorkloads runs benchmarkLRU on all hits, half misses and all misses
+func benchmarkLRUWorkloads(b *testing.B, newCache func() lruBench) {
+	r := rand.New(rand.NewSource(1))
+	workload := func(keySpace int) []int {
+		keys := make([]int, 1<<16)
+		for i := range keys {
+			keys[i] = r.Intn(keySpace)
+		}
+		return keys
+	}
+	allMisses := make([]int, 1<<16) // Never-seen keys, every access evicts
+	for i := range allMisses {
+		allMisses[i] = lruBenchmarkCapacity + i
+	}
+	workloads := []struct {
+		name string
+		keys []int
+	}{
+		{"hits=100%", workload(lruBenchmarkCapacity)},
+		{"hits=50%", workload(lruBenchmarkCapacity * 2)},
+		{"hits=0%", allMisses},
+	}
+	for _, workload := range workloads {
+		b.Run(workload.name, func(b *testing.B) {
+			benchmarkLRU(b, newCache, workload.keys)
+		})
+	}
+}
+
+func BenchmarkLRUArena(b *testing.B) {
+	benchmarkLRUWorkloads(b, func() lruBench { return NewCache[int, int](lruBenchmarkCapacity) })
+}
+
+func BenchmarkLRUPointerNodes(b *testing.B) {
+	benchmarkLRUWorkloads(b, func() lruBench { return newPointerCache(lruBenchmarkCapacity) })
+}
+
+func BenchmarkLRUContainerList(b *testing.B) {
+	benchmarkLRUWorkloads(b, func() lruBench { return newStdListCache(lruBenchmarkCapacity) })
+}
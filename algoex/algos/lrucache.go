@@ -1,115 +1,427 @@
 package algos
 
-import "fmt"
+import (
+	"fmt"
+	"go-ex/pkg/cachestats"
+	"math"
+	"sync"
+	"time"
+)
 
-// Node represents a single element in the doubly linked list.
-type Node struct {
-	key   string
-	value int
-	prev  *Node
-	next  *Node
+// Interface is the API shared by the caches in this package.
+type Interface[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Peek(key K) (V, bool)
+	Contains(key K) bool
+	Set(key K, value V)
+	Remove(key K) bool
+	Len() int
+	Keys() []K
+	Resize(capacity int) int
+	Purge()
+	OnEvict(fn func(key K, value V))
+	Stats() cachestats.Stats
+}
+
+var (
+	_ Interface[string, int] = (*Cache[string, int])(nil)
+	_ Interface[string, int] = (*SyncCache[string, int])(nil)
+)
+
+// nilIndex marks the end of a list in Cache's arena
+const nilIndex int32 = -1
+
+// maxCapacity keeps every arena index within an int32
+const maxCapacity = math.MaxInt32 - 1
+
+// entry is a slot in Cache's arena. prev and next are arena indexes, and free slots are
+// chained through next.
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	prev    int32
+	next    int32
+	expires int64 // Deadline in UnixNano, 0 if the entry never expires
+	index   int32 // Position in the expiry heap while expires is set
 }
 
 // Cache is the main struct for our LRU cache. It holds a map
 // for O(1) lookups and a doubly linked list for order.
-type Cache struct {
+// The list lives in an arena: a slice of entries linked by index rather than by pointer, whose
+// freed slots are reused through a free list. Once the cache is full, Set and Get allocate
+// nothing, and the GC sees one slice instead of a pointer per entry.
+// It is not safe for concurrent use; see SyncCache for that.
+type Cache[K comparable, V any] struct {
 	capacity int
 	length   int
-	head     *Node
-	tail     *Node
-	data     map[string]*Node
+	entries  []entry[K, V] // The arena
+	head     int32         // Most recently used
+	tail     int32         // Least recently used
+	free     int32         // First free slot
+	data     map[K]int32
+	expiry   expiryHeap[K, V] // Entries with a TTL, soonest deadline first
+	now      func() time.Time
+	onEvict  func(key K, value V)
+	stats    cachestats.Counters
+}
+
+// NewCache creates and returns a new Cache instance with a given capacity (at least 1).
+func NewCache[K comparable, V any](capacity int) *Cache[K, V] {
+	c := &Cache[K, V]{
+		capacity: min(max(capacity, 1), maxCapacity),
+		head:     nilIndex,
+		tail:     nilIndex,
+		free:     nilIndex,
+		data:     make(map[K]int32),
+		now:      time.Now,
+	}
+	c.expiry.entries = &c.entries
+	return c
+}
+
+// OnEvict registers fn to be called with every entry evicted to respect the capacity
+// (by Set or Resize). Entries removed with Remove or Purge are not reported.
+func (c *Cache[K, V]) OnEvict(fn func(key K, value V)) {
+	c.onEvict = fn
 }
 
-// NewCache creates and returns a new Cache instance with a given capacity.
-func NewCache(capacity int) *Cache {
-	return &Cache{
-		capacity: capacity,
-		length:   0,
-		head:     nil,
-		tail:     nil,
-		data:     make(map[string]*Node),
+// alloc stores key and value in a free slot, growing the arena only if there is none.
+// This is an internal helper method.
+func (c *Cache[K, V]) alloc(key K, value V) int32 {
+	if i := c.free; i != nilIndex {
+		c.free = c.entries[i].next
+		c.entries[i] = entry[K, V]{key: key, value: value}
+		return i
 	}
+	c.entries = append(c.entries, entry[K, V]{key: key, value: value})
+	return int32(len(c.entries) - 1)
 }
 
-// addNode adds a new node to the head of the doubly linked list.
+// release puts slot i on the free list, zeroing it so the GC can collect what it referenced.
 // This is an internal helper method.
-func (c *Cache) addNode(node *Node) {
-	node.prev = nil
-	node.next = c.head
+func (c *Cache[K, V]) release(i int32) {
+	c.entries[i] = entry[K, V]{next: c.free}
+	c.free = i
+}
 
-	if c.head != nil {
-		c.head.prev = node
+// addNode adds slot i to the head of the doubly linked list.
+// This is an internal helper method.
+func (c *Cache[K, V]) addNode(i int32) {
+	e := &c.entries[i]
+	e.prev = nilIndex
+	e.next = c.head
+
+	if c.head != nilIndex {
+		c.entries[c.head].prev = i
 	}
-	c.head = node
+	c.head = i
 
-	if c.tail == nil {
-		c.tail = node
+	if c.tail == nilIndex {
+		c.tail = i
 	}
 	c.length++
 }
 
-// removeNode removes a node from the doubly linked list.
+// removeNode removes slot i from the doubly linked list.
 // This is an internal helper method.
-func (c *Cache) removeNode(node *Node) {
-	if node.prev != nil {
-		node.prev.next = node.next
+func (c *Cache[K, V]) removeNode(i int32) {
+	e := &c.entries[i]
+	if e.prev != nilIndex {
+		c.entries[e.prev].next = e.next
 	} else {
 		// Node is the head
-		c.head = node.next
+		c.head = e.next
 	}
 
-	if node.next != nil {
-		node.next.prev = node.prev
+	if e.next != nilIndex {
+		c.entries[e.next].prev = e.prev
 	} else {
 		// Node is the tail
-		c.tail = node.prev
+		c.tail = e.prev
 	}
 	c.length--
 }
 
-// moveToHead moves an existing node to the head of the linked list.
+// moveToHead moves an existing slot to the head of the linked list.
 // This signifies that it has been "recently used."
 // This is an internal helper method.
-func (c *Cache) moveToHead(node *Node) {
-	c.removeNode(node)
-	c.addNode(node)
+func (c *Cache[K, V]) moveToHead(i int32) {
+	if c.head == i {
+		return
+	}
+	c.removeNode(i)
+	c.addNode(i)
+}
+
+// drop removes slot i from the list, the map and the expiry heap, and frees it.
+// This is an internal helper method.
+func (c *Cache[K, V]) drop(i int32) {
+	c.removeNode(i)
+	delete(c.data, c.entries[i].key)
+	c.setExpiry(i, 0)
+	c.release(i)
+}
+
+// evictTail removes the least recently used entry and reports it to OnEvict.
+// This is an internal helper method.
+func (c *Cache[K, V]) evictTail() {
+	key, value := c.entries[c.tail].key, c.entries[c.tail].value
+	c.drop(c.tail)
+	c.stats.Evict()
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
 }
 
 // Get retrieves a value from the cache. If the key exists, it moves the node
 // to the front of the list to mark it as most recently used.
-func (c *Cache) Get(key string) (int, bool) {
-	if node, ok := c.data[key]; ok {
-		c.moveToHead(node)
-		return node.value, true
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	if i, ok := c.live(key); ok {
+		c.moveToHead(i)
+		c.stats.Hit()
+		return c.entries[i].value, true
 	}
-	return 0, false
+	c.stats.Miss()
+	var zero V
+	return zero, false
+}
+
+// Peek retrieves a value without marking it as recently used.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	if i, ok := c.live(key); ok {
+		return c.entries[i].value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Contains reports whether key is in the cache without marking it as recently used.
+func (c *Cache[K, V]) Contains(key K) bool {
+	_, ok := c.live(key)
+	return ok
 }
 
 // Set adds a new key-value pair to the cache or updates an existing one.
-func (c *Cache) Set(key string, value int) {
-	if node, ok := c.data[key]; ok {
+// The entry never expires, even if it had a TTL before.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.set(key, value, 0)
+}
+
+// set stores an entry with the given deadline (0 for none).
+// This is an internal helper method.
+func (c *Cache[K, V]) set(key K, value V, expires int64) {
+	c.stats.Set()
+	if i, ok := c.data[key]; ok {
 		// Key exists, update the value and move to head.
-		node.value = value
-		c.moveToHead(node)
+		c.entries[i].value = value
+		c.setExpiry(i, expires)
+		c.moveToHead(i)
 		return
 	}
 
-	// Key is new
-	node := &Node{key: key, value: value}
-	c.data[key] = node
-	c.addNode(node)
+	// Key is new. Make room first, removing expired entries or else the tail, so its slot is reused.
+	if c.length >= c.capacity {
+		c.purgeExpired()
+		if c.length >= c.capacity {
+			c.evictTail()
+		}
+	}
+	i := c.alloc(key, value)
+	c.data[key] = i
+	c.addNode(i)
+	c.setExpiry(i, expires)
+}
 
-	// Check if we've exceeded capacity, and if so, remove the tail.
+// Remove deletes key from the cache and reports whether it was present.
+func (c *Cache[K, V]) Remove(key K) bool {
+	i, ok := c.data[key]
+	if !ok {
+		return false
+	}
+	c.drop(i)
+	c.stats.Delete()
+	return true
+}
+
+// Len returns the number of entries in the cache, counting expired ones not removed yet.
+func (c *Cache[K, V]) Len() int {
+	return c.length
+}
+
+// Keys returns the keys from most to least recently used, including expired ones not removed yet.
+func (c *Cache[K, V]) Keys() []K {
+	keys := make([]K, 0, c.length)
+	for i := c.head; i != nilIndex; i = c.entries[i].next {
+		keys = append(keys, c.entries[i].key)
+	}
+	return keys
+}
+
+// Resize changes the capacity (at least 1), evicting the least recently used entries
+// that no longer fit. It returns how many entries were evicted.
+func (c *Cache[K, V]) Resize(capacity int) int {
+	c.capacity = min(max(capacity, 1), maxCapacity)
 	if c.length > c.capacity {
-		tailKey := c.tail.key
-		c.removeNode(c.tail)
-		delete(c.data, tailKey)
+		c.purgeExpired()
 	}
+	evicted := 0
+	for c.length > c.capacity {
+		c.evictTail()
+		evicted++
+	}
+	if len(c.entries) > c.capacity {
+		c.compact()
+	}
+	return evicted
+}
+
+// compact moves the entries into a new arena without free slots, in recency order,
+// so shrinking the cache gives the memory back.
+// This is an internal helper method.
+func (c *Cache[K, V]) compact() {
+	entries := make([]entry[K, V], 0, c.length)
+	for i := c.head; i != nilIndex; i = c.entries[i].next {
+		e := c.entries[i]
+		n := int32(len(entries))
+		e.prev, e.next = n-1, n+1
+		entries = append(entries, e)
+		c.data[e.key] = n
+	}
+	if n := int32(len(entries)); n > 0 {
+		entries[0].prev, entries[n-1].next = nilIndex, nilIndex
+		c.head, c.tail = 0, n-1
+	}
+	for pos, slot := range c.expiry.slots {
+		c.expiry.slots[pos] = c.data[c.entries[slot].key]
+	}
+	c.entries = entries
+	c.free = nilIndex
+}
+
+// Purge removes every entry. The arena is kept for reuse.
+func (c *Cache[K, V]) Purge() {
+	clear(c.entries)
+	c.entries = c.entries[:0]
+	clear(c.data)
+	c.expiry.slots = c.expiry.slots[:0]
+	c.head, c.tail, c.free = nilIndex, nilIndex, nilIndex
+	c.length = 0
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters and current size
+func (c *Cache[K, V]) Stats() cachestats.Stats {
+	return c.stats.Snapshot(c.length)
+}
+
+// SyncCache is a Cache guarded by a mutex, safe for concurrent use.
+// A plain Mutex is used because even Get reorders the list.
+// OnEvict callbacks run with the lock held and must not call back into the cache.
+type SyncCache[K comparable, V any] struct {
+	mu    sync.Mutex
+	cache *Cache[K, V]
+}
+
+// NewSyncCache creates a SyncCache with the given capacity.
+func NewSyncCache[K comparable, V any](capacity int) *SyncCache[K, V] {
+	return &SyncCache[K, V]{cache: NewCache[K, V](capacity)}
+}
+
+func (c *SyncCache[K, V]) OnEvict(fn func(key K, value V)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.OnEvict(fn)
+}
+
+func (c *SyncCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Get(key)
+}
+
+func (c *SyncCache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Peek(key)
+}
+
+func (c *SyncCache[K, V]) Contains(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Contains(key)
+}
+
+func (c *SyncCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Set(key, value)
+}
+
+func (c *SyncCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.SetWithTTL(key, value, ttl)
+}
+
+func (c *SyncCache[K, V]) TTL(key K) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.TTL(key)
+}
+
+func (c *SyncCache[K, V]) PurgeExpired(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.PurgeExpired(now)
+}
+
+func (c *SyncCache[K, V]) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.SetClock(now)
+}
+
+func (c *SyncCache[K, V]) Remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Remove(key)
+}
+
+func (c *SyncCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Len()
+}
+
+func (c *SyncCache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Keys()
+}
+
+func (c *SyncCache[K, V]) Resize(capacity int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Resize(capacity)
+}
+
+func (c *SyncCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Purge()
+}
+
+func (c *SyncCache[K, V]) Stats() cachestats.Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Stats()
 }
 
 func RunLRUCache() {
 	// Create a new LRU cache with a capacity of 3.
-	lru := NewCache(3)
+	lru := NewCache[string, int](3)
+	lru.OnEvict(func(key string, value int) {
+		fmt.Printf("  (evicted %s=%d)\n", key, value)
+	})
 
 	fmt.Println("Setting key-value pairs...")
 	lru.Set("a", 10) // Cache: {a: 10}
@@ -127,8 +439,31 @@ func RunLRUCache() {
 	val, ok = lru.Get("a")
 	fmt.Printf("Get('a') exists? %t\n", ok) // Should be false
 
-	fmt.Println("\nAdding 'e', which will evict 'c'.")
+	fmt.Println("\nPeeking at 'c' does not save it from eviction.")
+	val, _ = lru.Peek("c")
+	fmt.Printf("Peek('c'): %d, keys by recency: %v\n", val, lru.Keys())
 	lru.Set("e", 50) // Cache: {e: 50, d: 40, b: 20}
-	val, ok = lru.Get("c")
-	fmt.Printf("Get('c') exists? %t\n", ok) // Should be false
+	fmt.Printf("Contains('c')? %t\n", lru.Contains("c"))
+
+	fmt.Println("\nShrinking to 1 entry.")
+	evicted := lru.Resize(1)
+	fmt.Printf("Resize(1) evicted %d, keys: %v\n", evicted, lru.Keys())
+
+	fmt.Printf("\nStats: %s\n", lru.Stats())
+
+	// The mutex-guarded variant can be shared between goroutines
+	shared := NewSyncCache[int, string](100)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				shared.Set(worker*50+j, fmt.Sprintf("value %d", j))
+				shared.Get(j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	fmt.Printf("SyncCache after 8 writers: len=%d, %s\n", shared.Len(), shared.Stats())
 }
@@ -0,0 +1,206 @@
+package algos
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// checkArena verifies the links of the recency list, the map and the free list of c
+func checkArena[K comparable, V any](t *testing.T, c *Cache[K, V]) {
+	t.Helper()
+	seen := 0
+	prev := nilIndex
+	for i := c.head; i != nilIndex; i = c.entries[i].next {
+		if c.entries[i].prev != prev {
+			t.Fatalf("slot %d has prev %d, want %d", i, c.entries[i].prev, prev)
+		}
+		if c.data[c.entries[i].key] != i {
+			t.Fatalf("map sends key %v to slot %d, list has it in slot %d", c.entries[i].key, c.data[c.entries[i].key], i)
+		}
+		prev = i
+		seen++
+	}
+	if c.tail != prev || seen != c.length || len(c.data) != c.length {
+		t.Fatalf("tail %d (want %d), %d linked, length %d, %d mapped", c.tail, prev, seen, c.length, len(c.data))
+	}
+	free := 0
+	for i := c.free; i != nilIndex; i = c.entries[i].next {
+		free++
+	}
+	if free+c.length != len(c.entries) {
+		t.Fatalf("%d free + %d used slots, arena has %d", free, c.length, len(c.entries))
+	}
+}
+
+func TestCacheRecencyOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		ops      string // Space separated: set:k get:k peek:k has:k rm:k resize:n
+		keys     string // Most to least recently used
+		evicted  string // In eviction order
+	}{
+		{"evicts least recently set", 2, "set:a set:b set:c", "c b", "a"},
+		{"get promotes", 2, "set:a set:b get:a set:c", "c a", "b"},
+		{"peek does not promote", 2, "set:a set:b peek:a set:c", "c b", "a"},
+		{"contains does not promote", 2, "set:a set:b has:a set:c", "c b", "a"},
+		{"update promotes without evicting", 2, "set:a set:b set:a set:c", "c a", "b"},
+		{"get of a missing key changes nothing", 2, "set:a set:b get:z set:c", "c b", "a"},
+		{"remove makes room", 2, "set:a set:b rm:a set:c", "c b", ""},
+		{"resize down evicts oldest first", 3, "set:a set:b set:c get:a resize:1", "a", "b c"},
+		{"resize up keeps entries", 2, "set:a set:b resize:3 set:c", "c b a", ""},
+		{"resize below one keeps one", 2, "set:a set:b resize:0", "b", "a"},
+		{"capacity below one means one", 0, "set:a set:b", "b", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](tt.capacity)
+			var evicted []string
+			c.OnEvict(func(key string, value int) { evicted = append(evicted, key) })
+			for n, op := range strings.Fields(tt.ops) {
+				name, arg, _ := strings.Cut(op, ":")
+				switch name {
+				case "set":
+					c.Set(arg, n)
+				case "get":
+					c.Get(arg)
+				case "peek":
+					c.Peek(arg)
+				case "has":
+					c.Contains(arg)
+				case "rm":
+					c.Remove(arg)
+				case "resize":
+					size, _ := strconv.Atoi(arg)
+					c.Resize(size)
+				}
+				checkArena(t, c)
+			}
+			if got := strings.Join(c.Keys(), " "); got != tt.keys {
+				t.Fatalf("Keys() = %q, want %q", got, tt.keys)
+			}
+			if got := strings.Join(evicted, " "); got != tt.evicted {
+				t.Fatalf("evicted %q, want %q", got, tt.evicted)
+			}
+			if stats := c.Stats(); stats.Evictions != uint64(len(evicted)) {
+				t.Fatalf("Stats().Evictions = %d, OnEvict saw %d", stats.Evictions, len(evicted))
+			}
+		})
+	}
+}
+
+func TestCacheOnEvictSkipsRemoveAndPurge(t *testing.T) {
+	c := NewCache[string, int](2)
+	evictions := 0
+	c.OnEvict(func(string, int) { evictions++ })
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Remove("a")
+	c.Purge()
+	if evictions != 0 || c.Len() != 0 || c.Contains("b") {
+		t.Fatalf("evictions=%d len=%d after Remove and Purge", evictions, c.Len())
+	}
+	c.Set("c", 3) // The cache still works after a Purge
+	if value, ok := c.Get("c"); !ok || value != 3 {
+		t.Fatalf("Get(c) = %d, %t", value, ok)
+	}
+	checkArena(t, c)
+}
+
+func TestCacheArenaReuse(t *testing.T) {
+	c := NewCache[int, int](4)
+	for i := 0; i < 4; i++ {
+		c.Set(i, i)
+	}
+	c.Remove(1)
+	c.Remove(2)
+	checkArena(t, c)
+	for i := 10; i < 100; i++ {
+		c.Set(i, i) // Removed and evicted slots are reused instead of growing the arena
+	}
+	if len(c.entries) != 4 {
+		t.Fatalf("arena grew to %d slots for a capacity of 4", len(c.entries))
+	}
+	checkArena(t, c)
+
+	// Shrinking compacts the arena in recency order
+	c.Get(97)
+	c.Resize(2)
+	if len(c.entries) != 2 || cap(c.entries) != 2 || c.free != nilIndex {
+		t.Fatalf("arena has %d slots (cap %d) after Resize(2)", len(c.entries), cap(c.entries))
+	}
+	if got := c.Keys(); !slices.Equal(got, []int{97, 99}) {
+		t.Fatalf("Keys() = %v after compact", got)
+	}
+	checkArena(t, c)
+	for i := 200; i < 210; i++ {
+		c.Set(i, i)
+		checkArena(t, c)
+	}
+}
+
+func TestCacheMatchesReferenceLRU(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	c := NewCache[int, int](8)
+	ref := newStdListCache(8)
+	for step := 0; step < 20000; step++ {
+		key := r.Intn(24)
+		switch op := r.Intn(100); {
+		case op < 40:
+			got, gotOK := c.Get(key)
+			want, wantOK := ref.Get(key)
+			if got != want || gotOK != wantOK {
+				t.Fatalf("step %d: Get(%d) = %d, %t, want %d, %t", step, key, got, gotOK, want, wantOK)
+			}
+		case op < 80:
+			c.Set(key, step)
+			ref.Set(key, step)
+		case op < 90:
+			if got, want := c.Remove(key), ref.Remove(key); got != want {
+				t.Fatalf("step %d: Remove(%d) = %t, want %t", step, key, got, want)
+			}
+		case op < 98:
+			value, ok := c.Peek(key)
+			if elem, found := ref.data[key]; found != ok || (ok && elem.Value.(*stdListEntry).value != value) {
+				t.Fatalf("step %d: Peek(%d) = %d, %t", step, key, value, ok)
+			}
+		default:
+			size := 1 + r.Intn(12)
+			c.Resize(size)
+			ref.Resize(size)
+		}
+		if got, want := c.Keys(), ref.Keys(); !slices.Equal(got, want) {
+			t.Fatalf("step %d: Keys() = %v, want %v", step, got, want)
+		}
+	}
+	checkArena(t, c)
+}
+
+func TestSyncCacheConcurrentUse(t *testing.T) {
+	c := NewSyncCache[int, int](64)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := (w*1000 + i) % 200
+				c.Set(key, i)
+				c.Get(key)
+				c.Peek(key + 1)
+				if i%100 == 0 {
+					c.Resize(32 + i%64)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if n := c.Len(); n == 0 || n > 95 {
+		t.Fatalf("Len() = %d", n)
+	}
+	checkArena(t, c.cache)
+}
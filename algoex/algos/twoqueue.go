@@ -0,0 +1,186 @@
+package algos
+
+import "go-ex/pkg/cachestats"
+
+// TwoQueueCache implements 2Q (Johnson & Shasha). New keys go into a small FIFO (recent) and
+// are only promoted to the main LRU (frequent) if they are set again after falling out of it,
+// which a ghost list of recently dropped keys remembers. A scan therefore only churns the FIFO
+// and the working set in the LRU survives it.
+// It is not safe for concurrent use.
+type TwoQueueCache[K comparable, V any] struct {
+	capacity   int
+	recentSize int // Target size of recent (Kin), a quarter of the capacity
+	ghostSize  int // Size of ghosts (Kout), half the capacity
+	recent     keyedList[K, V]
+	frequent   keyedList[K, V]
+	ghosts     keyedList[K, V] // Keys recently dropped from recent, without values
+	onEvict    func(key K, value V)
+	stats      cachestats.Counters
+}
+
+var _ Interface[string, int] = (*TwoQueueCache[string, int])(nil)
+
+// NewTwoQueueCache creates a 2Q cache holding up to capacity (at least 1) entries.
+func NewTwoQueueCache[K comparable, V any](capacity int) *TwoQueueCache[K, V] {
+	c := &TwoQueueCache[K, V]{}
+	c.Purge()
+	c.setCapacity(capacity)
+	return c
+}
+
+func (c *TwoQueueCache[K, V]) setCapacity(capacity int) {
+	c.capacity = max(capacity, 1)
+	c.recentSize = max(c.capacity/4, 1)
+	c.ghostSize = max(c.capacity/2, 1)
+}
+
+// OnEvict registers fn to be called with every entry evicted to respect the capacity.
+// Entries removed with Remove or Purge are not reported.
+func (c *TwoQueueCache[K, V]) OnEvict(fn func(key K, value V)) {
+	c.onEvict = fn
+}
+
+// lookup returns the node of a cached key.
+func (c *TwoQueueCache[K, V]) lookup(key K) (*Node[K, V], bool) {
+	if node, ok := c.frequent.nodes[key]; ok {
+		return node, true
+	}
+	node, ok := c.recent.nodes[key]
+	return node, ok
+}
+
+// evict drops one entry: the oldest of recent if it is over its target (remembering its key
+// as a ghost), else the least recently used of frequent.
+func (c *TwoQueueCache[K, V]) evict() {
+	var key K
+	var value V
+	if c.recent.length > c.recentSize || c.frequent.length == 0 {
+		node := c.recent.pop()
+		key, value = node.key, node.value
+		node.value = *new(V) // The node lives on as the ghost, without holding on to the value
+		c.ghosts.push(node)
+		if c.ghosts.length > c.ghostSize {
+			c.ghosts.pop()
+		}
+	} else {
+		node := c.frequent.pop()
+		key, value = node.key, node.value
+	}
+	c.stats.Evict()
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
+}
+
+// Get retrieves a value. Only hits in frequent change the order; recent is a FIFO.
+func (c *TwoQueueCache[K, V]) Get(key K) (V, bool) {
+	if node, ok := c.frequent.nodes[key]; ok {
+		c.frequent.moveToHead(node)
+		c.stats.Hit()
+		return node.value, true
+	}
+	if node, ok := c.recent.nodes[key]; ok {
+		c.stats.Hit()
+		return node.value, true
+	}
+	c.stats.Miss()
+	var zero V
+	return zero, false
+}
+
+// Peek retrieves a value without marking it as recently used.
+func (c *TwoQueueCache[K, V]) Peek(key K) (V, bool) {
+	if node, ok := c.lookup(key); ok {
+		return node.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Contains reports whether key is in the cache without marking it as recently used.
+func (c *TwoQueueCache[K, V]) Contains(key K) bool {
+	_, ok := c.lookup(key)
+	return ok
+}
+
+// Set adds or updates an entry. A key found among the ghosts goes straight into frequent.
+func (c *TwoQueueCache[K, V]) Set(key K, value V) {
+	c.stats.Set()
+	if node, ok := c.frequent.nodes[key]; ok {
+		node.value = value
+		c.frequent.moveToHead(node)
+		return
+	}
+	if node, ok := c.recent.nodes[key]; ok {
+		node.value = value
+		return
+	}
+
+	// Take the ghost off first so evicting can't push it out
+	ghost, wasGhost := c.ghosts.nodes[key]
+	if wasGhost {
+		c.ghosts.unlink(ghost)
+	}
+	if c.Len() >= c.capacity {
+		c.evict()
+	}
+	if wasGhost {
+		ghost.value = value
+		c.frequent.push(ghost)
+		return
+	}
+	c.recent.push(&Node[K, V]{key: key, value: value})
+}
+
+// Remove deletes key from the cache and reports whether it was present.
+func (c *TwoQueueCache[K, V]) Remove(key K) bool {
+	if ghost, ok := c.ghosts.nodes[key]; ok {
+		c.ghosts.unlink(ghost)
+	}
+	if node, ok := c.frequent.nodes[key]; ok {
+		c.frequent.unlink(node)
+	} else if node, ok := c.recent.nodes[key]; ok {
+		c.recent.unlink(node)
+	} else {
+		return false
+	}
+	c.stats.Delete()
+	return true
+}
+
+// Len returns the number of entries in the cache (ghosts don't count).
+func (c *TwoQueueCache[K, V]) Len() int {
+	return c.recent.length + c.frequent.length
+}
+
+// Keys returns the keys of frequent from most to least recently used, then those of recent, newest first.
+func (c *TwoQueueCache[K, V]) Keys() []K {
+	return append(c.frequent.keys(), c.recent.keys()...)
+}
+
+// Resize changes the capacity (at least 1) and the queue sizes derived from it, evicting
+// entries that no longer fit. It returns how many entries were evicted.
+func (c *TwoQueueCache[K, V]) Resize(capacity int) int {
+	c.setCapacity(capacity)
+	evicted := 0
+	for c.Len() > c.capacity {
+		c.evict()
+		evicted++
+	}
+	for c.ghosts.length > c.ghostSize {
+		c.ghosts.pop()
+	}
+	return evicted
+}
+
+// Purge removes every entry and forgets the ghosts.
+func (c *TwoQueueCache[K, V]) Purge() {
+	c.recent = newKeyedList[K, V]()
+	c.frequent = newKeyedList[K, V]()
+	c.ghosts = newKeyedList[K, V]()
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters and current size
+func (c *TwoQueueCache[K, V]) Stats() cachestats.Stats {
+	return c.stats.Snapshot(c.Len())
+}
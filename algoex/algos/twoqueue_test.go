@@ -0,0 +1,186 @@
+package algos
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkTwoQueue verifies the queue limits of c and that no key is on two lists
+func checkTwoQueue[K comparable, V any](t *testing.T, c *TwoQueueCache[K, V]) {
+	t.Helper()
+	if c.Len() > c.capacity || c.ghosts.length > c.ghostSize {
+		t.Fatalf("len %d (capacity %d), ghosts %d (Kout %d)", c.Len(), c.capacity, c.ghosts.length, c.ghostSize)
+	}
+	seen := make(map[K]bool)
+	for _, l := range []*keyedList[K, V]{&c.recent, &c.frequent, &c.ghosts} {
+		if len(l.nodes) != l.length {
+			t.Fatalf("list has %d nodes indexed, %d linked", len(l.nodes), l.length)
+		}
+		for _, key := range l.keys() {
+			if seen[key] {
+				t.Fatalf("key %v is on two lists", key)
+			}
+			seen[key] = true
+		}
+	}
+}
+
+func TestTwoQueueSizes(t *testing.T) {
+	tests := []struct {
+		capacity, kin, kout int
+	}{
+		{0, 1, 1},
+		{1, 1, 1},
+		{3, 1, 1},
+		{8, 2, 4},
+		{100, 25, 50},
+	}
+	for _, tt := range tests {
+		c := NewTwoQueueCache[int, int](tt.capacity)
+		if c.recentSize != tt.kin || c.ghostSize != tt.kout {
+			t.Errorf("capacity %d: Kin=%d Kout=%d, want %d and %d", tt.capacity, c.recentSize, c.ghostSize, tt.kin, tt.kout)
+		}
+	}
+
+	// Resizing recomputes both and trims the ghosts
+	c := NewTwoQueueCache[int, int](100)
+	for i := 0; i < 200; i++ {
+		c.Set(i, i)
+	}
+	if c.ghosts.length != 50 {
+		t.Fatalf("%d ghosts, want Kout=50", c.ghosts.length)
+	}
+	if evicted := c.Resize(8); evicted != 92 {
+		t.Fatalf("Resize(8) evicted %d, want 92", evicted)
+	}
+	if c.recentSize != 2 || c.ghostSize != 4 || c.ghosts.length != 4 {
+		t.Fatalf("after Resize(8): Kin=%d Kout=%d ghosts=%d", c.recentSize, c.ghostSize, c.ghosts.length)
+	}
+	checkTwoQueue(t, c)
+}
+
+func TestTwoQueuePromotion(t *testing.T) {
+	c := NewTwoQueueCache[int, int](8) // Kin 2, Kout 4
+	for i := 0; i < 8; i++ {
+		c.Set(i, i)
+	}
+
+	// recent is a FIFO: neither a hit nor an update moves or promotes a key
+	c.Get(3)
+	c.Set(3, 30)
+	if got := c.recent.keys(); !slices.Equal(got, []int{7, 6, 5, 4, 3, 2, 1, 0}) {
+		t.Fatalf("recent = %v", got)
+	}
+
+	c.Set(8, 8) // Full: the oldest of recent, 0, becomes a ghost
+	if got := c.ghosts.keys(); !slices.Equal(got, []int{0}) || c.Contains(0) {
+		t.Fatalf("ghosts = %v, Contains(0) = %t", got, c.Contains(0))
+	}
+	if _, ok := c.ghosts.nodes[0]; !ok || c.ghosts.nodes[0].value != 0 {
+		t.Fatal("ghost of 0 missing or still holding a value")
+	}
+
+	c.Set(0, 100) // A ghost hit goes straight to frequent
+	if got := c.frequent.keys(); !slices.Equal(got, []int{0}) {
+		t.Fatalf("frequent = %v after the ghost hit", got)
+	}
+	if value, _ := c.Peek(0); value != 100 {
+		t.Fatalf("Peek(0) = %d, want the new value", value)
+	}
+
+	// Kout: only the 4 most recent ghosts are remembered, so 1 and 2 are forgotten
+	for i := 9; i <= 13; i++ {
+		c.Set(i, i)
+	}
+	if got := c.ghosts.keys(); !slices.Equal(got, []int{6, 5, 4, 3}) {
+		t.Fatalf("ghosts = %v, want [6 5 4 3]", got)
+	}
+	c.Set(1, 1) // Forgotten, so a plain new key
+	if !slices.Contains(c.recent.keys(), 1) {
+		t.Fatalf("1 was promoted without a ghost: frequent = %v", c.frequent.keys())
+	}
+	c.Set(4, 4) // Still a ghost
+	if got := c.frequent.keys(); !slices.Equal(got, []int{4, 0}) {
+		t.Fatalf("frequent = %v, want [4 0]", got)
+	}
+	checkTwoQueue(t, c)
+}
+
+func TestTwoQueueEvictsFrequentOnceRecentIsAtKin(t *testing.T) {
+	c := NewTwoQueueCache[int, int](8) // Kin 2, Kout 4
+	var evicted []int
+	c.OnEvict(func(key, value int) { evicted = append(evicted, key) })
+	for i := 0; i < 12; i++ {
+		c.Set(i, i) // recent [11..4], ghosts [3 2 1 0]
+	}
+	for _, key := range []int{0, 1, 2, 3, 4, 5} {
+		c.Set(key, key) // Ghost hits, each pushing the oldest of recent out while it is over Kin
+	}
+	if got := c.recent.keys(); !slices.Equal(got, []int{11, 10}) {
+		t.Fatalf("recent = %v, want it down to Kin", got)
+	}
+
+	evicted = nil
+	c.Set(6, 6) // recent is at Kin, so the least recently used of frequent goes
+	if !slices.Equal(evicted, []int{0}) || !slices.Equal(c.recent.keys(), []int{11, 10}) {
+		t.Fatalf("evicted %v, recent = %v", evicted, c.recent.keys())
+	}
+	c.Set(100, 100)
+	c.Set(101, 101) // recent went over Kin, so its oldest goes
+	if !slices.Equal(evicted, []int{0, 1, 10}) {
+		t.Fatalf("evicted %v, want [0 1 10]", evicted)
+	}
+	checkTwoQueue(t, c)
+}
+
+func TestTwoQueueKeepsFrequentThroughScan(t *testing.T) {
+	c := NewTwoQueueCache[int, int](8)
+	hot := []int{-1, -2, -3}  // Few enough to all be remembered as ghosts
+	for _, key := range hot { // Set, push out as ghosts, set again: now in frequent
+		c.Set(key, key)
+	}
+	for i := 0; i < 8; i++ {
+		c.Set(1000+i, i)
+	}
+	for _, key := range hot {
+		c.Set(key, key)
+	}
+	for i := 0; i < 500; i++ { // A long scan of keys never seen again
+		c.Set(i, i)
+		checkTwoQueue(t, c)
+	}
+	for _, key := range hot {
+		if !c.Contains(key) {
+			t.Fatalf("hot key %d was washed out by the scan; frequent = %v", key, c.frequent.keys())
+		}
+	}
+}
+
+func TestTwoQueueInvariantsUnderRandomOps(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	c := NewTwoQueueCache[int, int](16)
+	for step := 0; step < 20000; step++ {
+		key := r.Intn(64)
+		switch op := r.Intn(100); {
+		case op < 45:
+			c.Get(key)
+		case op < 90:
+			c.Set(key, step)
+		case op < 98:
+			c.Remove(key)
+		default:
+			c.Resize(1 + r.Intn(32))
+		}
+		checkTwoQueue(t, c)
+	}
+}
+
+func TestTwoQueueBeatsLRUOnShortScans(t *testing.T) {
+	trace := ScanTrace(80, 20_000, 500, 60, 1)
+	lru := ReplayTrace(NewCache[int, int](100), trace)
+	twoQueue := ReplayTrace(NewTwoQueueCache[int, int](100), trace)
+	if twoQueue <= lru {
+		t.Fatalf("2Q hit ratio %.3f, LRU %.3f", twoQueue, lru)
+	}
+}
@@ -15,6 +15,15 @@ func main() {
 	case "lru":
 		fmt.Println("LRU Cache Program...")
 		algos.RunLRUCache()
+	case "lruttl":
+		fmt.Println("LRU Cache with TTLs Program...")
+		algos.RunLRUCacheTTL()
+	case "lfu":
+		fmt.Println("LFU Cache Program...")
+		algos.RunLFUCache()
+	case "scanresistance":
+		fmt.Println("LRU vs 2Q vs ARC Program...")
+		algos.RunScanResistance()
 	default:
 		fmt.Println("Invalid program choice. Please choose a valid program")
 	}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-ex/customcache"
+	"go-ex/customcache/httpapi"
+	"go-ex/customcache/memcached"
+	"go-ex/customcache/resp"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// cacheserver runs a customcache.ConcurrentCache as a standalone sidecar process.
+// Example: go run ./cacheserver -addr 127.0.0.1:11211 -protocol memcached
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:11211", "TCP address to listen on")
+	protocol := flag.String("protocol", "memcached", "Wire protocol to serve: memcached, resp or http")
+	cleanup := flag.Duration("cleanup", time.Minute, "How often expired items are swept")
+	verbose := flag.Bool("verbose", false, "Print every cache operation")
+	maxEntries := flag.Int("ns-max-entries", 0, "Entry quota of every namespace, including the \"default\" one memcached and resp serve (0 means no limit)")
+	maxBytes := flag.Int64("ns-max-bytes", 0, "Byte quota of every namespace, including the \"default\" one memcached and resp serve (0 means no limit)")
+	maxNamespaces := flag.Int("ns-max", 16, "http: how many namespaces besides \"default\" clients may create")
+	maxBulk := flag.Int("resp-max-bulk", resp.DefaultMaxBulkBytes, "resp: longest key or value a client may send, in bytes")
+	flag.Parse()
+
+	config := customcache.ConcurrentCacheConfig{
+		Quiet:           !*verbose,
+		CleanupInterval: *cleanup,
+	}
+	// memcached and resp serve a single cache; http serves namespaces (plain paths use "default")
+	namespaces := customcache.NewNamespacedCache(customcache.NamespacedCacheConfig{
+		Base:          config,
+		DefaultQuota:  customcache.NamespaceQuota{MaxEntries: *maxEntries, MaxBytes: *maxBytes},
+		MaxNamespaces: *maxNamespaces,
+	})
+	cache, _ := namespaces.Lookup(customcache.DefaultNamespace) // Always exists
+
+	var server interface {
+		ListenAndServe(addr string) error
+		Close() error
+	}
+	switch *protocol {
+	case "memcached":
+		server = memcached.NewServer(cache)
+	case "resp":
+		server = resp.NewServerWithConfig(cache, resp.ServerConfig{MaxBulkBytes: *maxBulk})
+	case "http":
+		server = &httpServer{server: &http.Server{Handler: httpapi.NewNamespacedHandler(namespaces)}}
+	default:
+		fmt.Printf("Invalid protocol %q. Please choose a valid protocol\n", *protocol)
+		namespaces.Close()
+		os.Exit(2)
+	}
+
+	// Shut down cleanly on Ctrl+C: close client connections, then stop the caches
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Println("Shutting down...")
+		server.Close()
+	}()
+
+	fmt.Printf("Serving %s protocol on %s\n", *protocol, *addr)
+	err := server.ListenAndServe(*addr)
+	namespaces.Close() // Not deferred, since os.Exit would skip it
+	if err != nil {
+		fmt.Printf("Server error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// httpServer adapts http.Server to the ListenAndServe/Close shape of the TCP protocol servers
+type httpServer struct {
+	server *http.Server
+}
+
+func (s *httpServer) ListenAndServe(addr string) error {
+	s.server.Addr = addr
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *httpServer) Close() error {
+	return s.server.Close()
+}
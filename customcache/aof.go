@@ -0,0 +1,400 @@
+package customcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Beyond snapshots, the cache can log every write to an append-only file (AOF) and replay it on startup.
+//
+// File format: the 8 byte aofMagic, then one record per write:
+//
+//	[4 byte big-endian payload length][4 byte CRC-32 of the payload][payload: gob-encoded aofRecord]
+//
+// Each payload has its own gob encoder, so every record can be decoded on its own. A crash can leave
+// a torn record at the end of the file; replay detects it by length or checksum, stops there and
+// truncates it so new records are appended after the last good one. A bad record anywhere else
+// is corruption, not a torn write, and fails the replay instead of silently dropping the rest.
+
+const aofMagic = "CCAOF001"
+
+// maxAOFRecordBytes caps the payload length replay will trust from a record header
+const maxAOFRecordBytes = 64 << 20
+
+// SyncPolicy controls how often the AOF is fsynced to disk
+type SyncPolicy int
+
+const (
+	SyncEverySecond SyncPolicy = iota // fsync once a second; a crash loses at most ~1s of writes
+	SyncAlways                        // fsync after every write; slowest but loses nothing
+	SyncNever                         // Leave flushing to the OS
+)
+
+type aofOp uint8
+
+const (
+	aofSet aofOp = iota + 1
+	aofDelete
+	aofTouch
+)
+
+// aofRecord is a single logged write
+type aofRecord struct {
+	Op        aofOp
+	Key       string
+	Value     interface{}
+	Flags     uint32
+	ExpiresAt time.Time
+	Tags      []string
+}
+
+// aofLog is the open append-only file
+type aofLog struct {
+	policy SyncPolicy
+	syncMu sync.Mutex // Held across an fsync or a rewrite, so the file isn't swapped mid-fsync
+
+	mu          sync.Mutex
+	path        string
+	file        *os.File
+	size        int64 // Current file size
+	rewriteSize int64 // File size right after the last compaction
+	dirty       bool  // Written since the last fsync
+}
+
+// encodeAOFRecord frames a record as length + checksum + payload
+func encodeAOFRecord(record aofRecord) ([]byte, error) {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(record); err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 8, 8+payload.Len())
+	binary.BigEndian.PutUint32(frame[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	return append(frame, payload.Bytes()...), nil
+}
+
+// openAOF opens (or creates) the log at path and replays it through apply.
+// It returns the log positioned for appending after the last good record.
+func openAOF(path string, policy SyncPolicy, apply func(aofRecord)) (*aofLog, int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	good, replayed, err := replayAOF(file, apply)
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	if good == 0 {
+		// New (or only a torn header): start the file with the magic
+		if _, err := file.WriteAt([]byte(aofMagic), 0); err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		good = int64(len(aofMagic))
+	}
+	// Drop a torn tail so appends continue right after the last good record
+	if err := file.Truncate(good); err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	if _, err := file.Seek(good, io.SeekStart); err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return &aofLog{policy: policy, path: path, file: file, size: good, rewriteSize: good}, replayed, nil
+}
+
+// replayAOF applies every intact record and returns the offset just past the last one.
+// Only a bad record at the very end of the file is treated as torn; one followed by more data is an error.
+func replayAOF(file *os.File, apply func(aofRecord)) (good int64, replayed int, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := info.Size()
+
+	r := bufio.NewReader(file)
+	magic := make([]byte, len(aofMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return 0, 0, nil // Empty or torn header: nothing to replay
+	}
+	if string(magic) != aofMagic {
+		return 0, 0, fmt.Errorf("customcache: %s is not an append-only log", file.Name())
+	}
+	good = int64(len(aofMagic))
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				fmt.Printf("Cache: skipping torn record header at offset %d of %s\n", good, file.Name())
+				return good, replayed, nil
+			}
+			if errors.Is(err, io.EOF) {
+				return good, replayed, nil
+			}
+			return good, replayed, err
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		sum := binary.BigEndian.Uint32(header[4:8])
+		end := good + 8 + length
+		if end > size {
+			// The payload runs past the end of the file: a write cut short by a crash
+			fmt.Printf("Cache: skipping torn record at offset %d of %s\n", good, file.Name())
+			return good, replayed, nil
+		}
+		if length > maxAOFRecordBytes {
+			return good, replayed, fmt.Errorf("customcache: record at offset %d of %s claims %d bytes, limit %d", good, file.Name(), length, maxAOFRecordBytes)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return good, replayed, err
+		}
+		if crc32.ChecksumIEEE(payload) != sum {
+			if end == size {
+				fmt.Printf("Cache: skipping torn record at offset %d of %s\n", good, file.Name())
+				return good, replayed, nil
+			}
+			return good, replayed, fmt.Errorf("customcache: checksum mismatch in record at offset %d of %s", good, file.Name())
+		}
+		var record aofRecord
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&record); err != nil {
+			return good, replayed, fmt.Errorf("customcache: undecodable record at offset %d of %s: %w", good, file.Name(), err)
+		}
+		apply(record)
+		replayed++
+		good = end
+	}
+}
+
+// append writes a record. It does not fsync; under SyncAlways the writer calls sync once it
+// has released the cache lock.
+func (l *aofLog) append(record aofRecord) error {
+	frame, err := encodeAOFRecord(record)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n, err := l.file.Write(frame)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	l.dirty = true
+	return nil
+}
+
+// sync fsyncs the log if anything was written since the last fsync. The fsync runs without
+// l.mu, so appends carry on meanwhile; their records are covered by the next sync.
+func (l *aofLog) sync() error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	l.mu.Lock()
+	file, dirty := l.file, l.dirty
+	l.dirty = false
+	l.mu.Unlock()
+	if !dirty {
+		return nil
+	}
+	if err := file.Sync(); err != nil {
+		l.mu.Lock()
+		l.dirty = true // Still not on disk
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// needsRewrite reports whether the log has grown past threshold and doubled since the last compaction
+func (l *aofLog) needsRewrite(threshold int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return threshold > 0 && l.size >= threshold && l.size >= 2*l.rewriteSize
+}
+
+// rewrite replaces the log with one Set record per live item
+func (l *aofLog) rewrite(data map[string]Item) error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var size int64
+	err := writeFileAtomic(l.path, func(w io.Writer) error {
+		n, err := io.WriteString(w, aofMagic)
+		size += int64(n)
+		if err != nil {
+			return err
+		}
+		for key, item := range data {
+			if item.expired(now) {
+				continue
+			}
+			frame, err := encodeAOFRecord(aofRecord{Op: aofSet, Key: key, Value: item.Value, Flags: item.Flags, ExpiresAt: item.ExpiresAt, Tags: item.Tags})
+			if err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+			n, err := w.Write(frame)
+			size += int64(n)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Switch appends over to the compacted file
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	l.file.Close()
+	l.file, l.size, l.rewriteSize, l.dirty = file, size, size, false
+	return nil
+}
+
+func (l *aofLog) close() error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	syncErr := l.file.Sync()
+	return errors.Join(syncErr, l.file.Close())
+}
+
+// applyAOF replays a logged write into the map (no stats, store writes or logging)
+func (c *ConcurrentCache) applyAOF(record aofRecord) {
+	switch record.Op {
+	case aofSet:
+		item := Item{Value: record.Value, Flags: record.Flags, Tags: record.Tags, ExpiresAt: record.ExpiresAt, CAS: c.cas.Add(1)}
+		if item.expired(time.Now()) {
+			c.remove(record.Key)
+			return
+		}
+		c.put(record.Key, item)
+	case aofDelete:
+		c.remove(record.Key)
+	case aofTouch:
+		if item, found := c.data[record.Key]; found {
+			item.ExpiresAt = record.ExpiresAt
+			c.data[record.Key] = item
+		}
+	}
+}
+
+// logWrite appends a write to the AOF, if one is configured. Called with c.mu held so the log
+// has the same order as the map.
+func (c *ConcurrentCache) logWrite(record aofRecord) {
+	if c.aof == nil {
+		return
+	}
+	if err := c.aof.append(record); err != nil {
+		c.storeFailed(record.Key, fmt.Errorf("append-only log: %w", err))
+	}
+}
+
+// unlockAndSync releases c.mu after a write to key and then, under SyncAlways, fsyncs the log.
+// The fsync runs outside the lock so a slow disk doesn't stall readers and other writers, but
+// the writer still returns only once its record is on disk.
+func (c *ConcurrentCache) unlockAndSync(key string) {
+	c.mu.Unlock()
+	if c.aof == nil || c.aof.policy != SyncAlways {
+		return
+	}
+	if err := c.aof.sync(); err != nil {
+		c.storeFailed(key, fmt.Errorf("append-only log fsync: %w", err))
+	}
+}
+
+// CompactAOF rewrites the append-only log from the live data, dropping overwritten and deleted
+// keys. It holds the read lock, so writes wait while the new log is written but reads carry on,
+// except those that arrive after a writer has started waiting (sync.RWMutex queues them behind it).
+func (c *ConcurrentCache) CompactAOF() error {
+	if c.aof == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.aof.rewrite(c.data)
+}
+
+// aofRoutine fsyncs the log every second (SyncEverySecond) and compacts it once it grows
+// past AOFRewriteSize, until the cache is closed
+func (c *ConcurrentCache) aofRoutine() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if c.config.AOFSync == SyncEverySecond {
+				if err := c.aof.sync(); err != nil {
+					fmt.Printf("Cache: append-only log fsync failed: %v\n", err)
+				}
+			}
+			if c.aof.needsRewrite(c.config.AOFRewriteSize) {
+				if err := c.CompactAOF(); err != nil {
+					fmt.Printf("Cache: append-only log compaction failed: %v\n", err)
+				}
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func RunAOF() {
+	dir, err := os.MkdirTemp("", "customcache-aof-")
+	if err != nil {
+		fmt.Printf("Failed to create AOF directory: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.aof")
+
+	config := ConcurrentCacheConfig{AOFPath: path, AOFSync: SyncAlways, Quiet: true}
+	cache := NewConcurrentCacheWithConfig(config)
+	for i := 0; i < 100; i++ {
+		cache.Set("counter", i) // 100 records for a single key
+	}
+	cache.Set("user:1", "Alice")
+	cache.Set("user:2", "Bob")
+	cache.Delete("user:2")
+	cache.Close()
+
+	// Simulate a crash in the middle of writing a record
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	f.Write([]byte{0, 0, 0, 42, 1, 2}) // A frame header promising 42 bytes that never arrived
+	f.Close()
+	before, _ := os.Stat(path)
+
+	config.Quiet = false // Show the replay
+	restarted := NewConcurrentCacheWithConfig(config)
+	counter, _ := restarted.Get("counter")
+	_, hasUser2 := restarted.Get("user:2")
+	fmt.Printf("Replayed: counter=%v user:2 found=%t\n", counter, hasUser2)
+
+	if err := restarted.CompactAOF(); err != nil {
+		fmt.Printf("Compaction failed: %v\n", err)
+	}
+	after, _ := os.Stat(path)
+	fmt.Printf("AOF size before compaction: %d bytes, after: %d bytes\n", before.Size(), after.Size())
+	restarted.Close()
+}
@@ -0,0 +1,153 @@
+package customcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestAOF writes a log holding a Set record for each key and returns its path
+func writeTestAOF(t *testing.T, keys ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	data := []byte(aofMagic)
+	for _, key := range keys {
+		frame, err := encodeAOFRecord(aofRecord{Op: aofSet, Key: key, Value: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, frame...)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func appendToFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAOFTruncatesTornTail(t *testing.T) {
+	tests := []struct {
+		name string
+		tail []byte
+	}{
+		{"short header", []byte{0, 0, 0}},
+		{"short payload", []byte{0, 0, 0, 42, 1, 2, 3, 4, 5}},
+		{"huge length", []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestAOF(t, "a", "b")
+			good, _ := os.Stat(path)
+			appendToFile(t, path, tt.tail)
+
+			var keys []string
+			log, replayed, err := openAOF(path, SyncNever, func(r aofRecord) { keys = append(keys, r.Key) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer log.close()
+			if replayed != 2 || len(keys) != 2 {
+				t.Fatalf("replayed %d records (%v), want 2", replayed, keys)
+			}
+			if info, _ := os.Stat(path); info.Size() != good.Size() {
+				t.Fatalf("log is %d bytes after opening, want the torn tail cut back to %d", info.Size(), good.Size())
+			}
+		})
+	}
+}
+
+func TestOpenAOFTruncatesBadChecksumAtEnd(t *testing.T) {
+	path := writeTestAOF(t, "a", "b")
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff // Damage the last record's payload
+	os.WriteFile(path, data, 0o644)
+
+	log, replayed, err := openAOF(path, SyncNever, func(aofRecord) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.close()
+	if replayed != 1 {
+		t.Fatalf("replayed %d records, want 1", replayed)
+	}
+}
+
+func TestOpenAOFRejectsCorruptRecordInTheMiddle(t *testing.T) {
+	path := writeTestAOF(t, "a", "b", "c")
+	data, _ := os.ReadFile(path)
+	data[len(aofMagic)+10] ^= 0xff // Damage the first record's payload
+	os.WriteFile(path, data, 0o644)
+
+	if log, _, err := openAOF(path, SyncNever, func(aofRecord) {}); err == nil {
+		log.close()
+		t.Fatal("openAOF accepted a log with a corrupt record before the end")
+	}
+	if after, _ := os.ReadFile(path); len(after) != len(data) {
+		t.Fatalf("log was cut from %d to %d bytes", len(data), len(after))
+	}
+}
+
+func TestAOFSyncAlwaysRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	config := ConcurrentCacheConfig{AOFPath: path, AOFSync: SyncAlways}
+	cache := NewConcurrentCacheWithConfig(config)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Delete("a")
+	if err := cache.CompactAOF(); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("c", 3)
+	cache.Close()
+
+	restarted := NewConcurrentCacheWithConfig(config)
+	defer restarted.Close()
+	if _, ok := restarted.Get("a"); ok {
+		t.Fatal("deleted key a came back")
+	}
+	for key, want := range map[string]int{"b": 2, "c": 3} {
+		if value, ok := restarted.Get(key); !ok || value != want {
+			t.Fatalf("Get(%s) = %v, %t, want %d", key, value, ok, want)
+		}
+	}
+}
+
+func TestNewConcurrentCacheEFailsOnBadLog(t *testing.T) {
+	corrupt := writeTestAOF(t, "a", "b", "c")
+	data, _ := os.ReadFile(corrupt)
+	data[len(aofMagic)+10] ^= 0xff // Damage the first record's payload
+	os.WriteFile(corrupt, data, 0o644)
+
+	for name, path := range map[string]string{
+		"corrupt record":    corrupt,
+		"missing directory": filepath.Join(t.TempDir(), "missing", "cache.aof"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := NewConcurrentCacheE(ConcurrentCacheConfig{AOFPath: path})
+			if err == nil {
+				cache.Close()
+				t.Fatal("NewConcurrentCacheE started a cache without its log")
+			}
+		})
+	}
+
+	cache, err := NewConcurrentCacheE(ConcurrentCacheConfig{AOFPath: writeTestAOF(t, "a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("key a was not replayed")
+	}
+}
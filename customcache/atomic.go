@@ -0,0 +1,274 @@
+package customcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Get followed by Set races: two goroutines can read the same counter and both write back +1.
+// The operations in this file read and write a key as a single step instead.
+//
+// Linearizability: without a write-through Store every operation here appears to take effect at one
+// instant between its call and its return, and all writes to a key (Set, Delete and these) are
+// totally ordered. The decision is made and applied under the cache's write lock, so no other write
+// can slip in between.
+//
+// With a write-through Store the store has to be written between the decision and the map update,
+// and holding the cache lock across that I/O would stall every reader. So the decision is made
+// under writeMu only, which every writer takes: writes are still totally ordered, in the store and
+// in the map, and each decision sees the write before it. What can slip in is the cache's own
+// housekeeping: an entry that expires or is evicted after the decision is put back by the update.
+//
+// A missing key is read through from the Store first, so e.g. Increment continues from the stored
+// value instead of overwriting it. Update callbacks run with a cache-wide lock held: keep them
+// short and don't call back into the cache from them.
+
+// Number is a numeric type Increment can add to
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// ErrNotNumber is returned by Increment when the current value is not of the requested type
+var ErrNotNumber = errors.New("customcache: value is not of the requested numeric type")
+
+// mutation is what a read-modify-write decided to do with a key
+type mutation int
+
+const (
+	keepKey   mutation = iota // Leave the key as it is
+	storeKey                  // Store the returned item
+	deleteKey                 // Delete the key
+)
+
+// liveItem returns the unexpired item of key. Called with c.mu held.
+func (c *ConcurrentCache) liveItem(key string) (Item, bool) {
+	item, found := c.data[key]
+	if !found || item.expired(time.Now()) {
+		return Item{}, false
+	}
+	if c.lru != nil {
+		c.lru.touch(key)
+	}
+	return item, true
+}
+
+// modify runs fn on the current item of key and applies what it decides as one atomic step.
+// It returns the store's error if a write-through store rejected the write.
+func (c *ConcurrentCache) modify(key string, fn func(current Item, found bool) (Item, mutation)) error {
+	if c.config.Store != nil {
+		c.mu.RLock()
+		_, cached := c.liveItem(key)
+		c.mu.RUnlock()
+		if !cached {
+			c.readThrough(key) // Start from the stored value, if there is one
+		}
+	}
+
+	if c.config.Store == nil || c.config.WriteMode != WriteThrough {
+		c.mu.Lock()
+		defer c.unlockAndSync(key)
+		item, op := fn(c.liveItem(key))
+		if op == storeKey {
+			var err error
+			if item, err = c.sized(key, item); err != nil {
+				return err
+			}
+		}
+		c.apply(key, item, op)
+		return nil
+	}
+
+	// Write-through: writeMu keeps out every other writer while the store is written
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.RLock()
+	current, found := c.liveItem(key)
+	c.mu.RUnlock()
+	item, op := fn(current, found)
+	if op == storeKey {
+		var err error
+		if item, err = c.sized(key, item); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch op {
+	case storeKey:
+		err = c.config.Store.Store(context.Background(), key, item.Value)
+	case deleteKey:
+		err = c.config.Store.Delete(context.Background(), key)
+	}
+	if err != nil {
+		c.storeFailed(key, err)
+		return err
+	}
+	c.mu.Lock()
+	defer c.unlockAndSync(key)
+	c.apply(key, item, op)
+	return nil
+}
+
+// apply carries out a mutation decided by modify. Called with c.mu held.
+func (c *ConcurrentCache) apply(key string, item Item, op mutation) {
+	switch op {
+	case storeKey:
+		c.storeLocked(key, item)
+	case deleteKey:
+		c.deleteLocked(key)
+	}
+}
+
+// valuesEqual compares two cached values. Values == would panic on (slices, maps and anything
+// holding them) are compared with bytes.Equal or reflect.DeepEqual instead.
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := a.([]byte); ok {
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.IsValid() && vb.IsValid() && (!va.Comparable() || !vb.Comparable()) {
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+// CompareAndSwap replaces the value of key with newValue if its current value equals oldValue.
+// The key keeps its TTL, flags and tags. Values are compared with ==, or with bytes.Equal or
+// reflect.DeepEqual for types == can't compare, such as []byte.
+func (c *ConcurrentCache) CompareAndSwap(key string, oldValue, newValue interface{}) bool {
+	swapped := false
+	err := c.modify(key, func(current Item, found bool) (Item, mutation) {
+		if !found || !valuesEqual(current.Value, oldValue) {
+			return current, keepKey
+		}
+		swapped = true
+		current.Value = newValue
+		return current, storeKey
+	})
+	return err == nil && swapped
+}
+
+// CompareAndDelete deletes key if its current value equals oldValue (compared as in CompareAndSwap)
+func (c *ConcurrentCache) CompareAndDelete(key string, oldValue interface{}) bool {
+	deleted := false
+	err := c.modify(key, func(current Item, found bool) (Item, mutation) {
+		if !found || !valuesEqual(current.Value, oldValue) {
+			return current, keepKey
+		}
+		deleted = true
+		return current, deleteKey
+	})
+	return err == nil && deleted
+}
+
+// GetOrSet returns the existing value of key if there is one (loaded is true).
+// Otherwise it stores value with DefaultTTL and returns it.
+func (c *ConcurrentCache) GetOrSet(key string, value interface{}) (actual interface{}, loaded bool) {
+	c.modify(key, func(current Item, found bool) (Item, mutation) {
+		if found {
+			actual, loaded = current.Value, true
+			return current, keepKey
+		}
+		actual = value
+		return Item{Value: value, ExpiresAt: expiresAt(c.config.DefaultTTL)}, storeKey
+	})
+	if loaded {
+		c.stats.Hit()
+	} else {
+		c.stats.Miss()
+	}
+	return actual, loaded
+}
+
+// Update replaces the value of key with fn(old, found) and returns the new value. fn runs with every
+// other writer locked out, so concurrent Updates of a key never lose each other's changes. An existing
+// key keeps its TTL, flags and tags; a new key gets DefaultTTL. The bool is false if the store
+// rejected the write, in which case the value is left unchanged.
+//
+// The lock is cache-wide, not per key: fn runs under the cache's write lock, which also blocks every
+// reader, or under writeMu with a write-through Store, which blocks every writer of every key. Keep fn
+// short and free of I/O, and don't call the cache from it (that deadlocks). Compute expensive values
+// beforehand and use CompareAndSwap to install them.
+func (c *ConcurrentCache) Update(key string, fn func(old interface{}, found bool) interface{}) (interface{}, bool) {
+	var result interface{}
+	err := c.modify(key, func(current Item, found bool) (Item, mutation) {
+		if !found {
+			current = Item{ExpiresAt: expiresAt(c.config.DefaultTTL)}
+		}
+		current.Value = fn(current.Value, found)
+		result = current.Value
+		return current, storeKey
+	})
+	if err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// Increment adds delta to the numeric value of key and returns the result. A missing key counts
+// as zero. It returns ErrNotNumber if the current value is not a T; integers wrap on overflow.
+func Increment[T Number](c *ConcurrentCache, key string, delta T) (T, error) {
+	var result T
+	var typeErr error
+	err := c.modify(key, func(current Item, found bool) (Item, mutation) {
+		if !found {
+			current = Item{ExpiresAt: expiresAt(c.config.DefaultTTL)}
+		} else if n, ok := current.Value.(T); ok {
+			result = n
+		} else {
+			typeErr = fmt.Errorf("%w: key %q holds %T, not %T", ErrNotNumber, key, current.Value, result)
+			return current, keepKey
+		}
+		result += delta
+		current.Value = result
+		return current, storeKey
+	})
+	if typeErr != nil {
+		return 0, typeErr
+	}
+	return result, err
+}
+
+func RunAtomicOps() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Quiet: true})
+	defer cache.Close()
+
+	// 100 goroutines bump the same counter; Get+Set would lose updates here
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Increment(cache, "page:views", 1)
+		}()
+	}
+	wg.Wait()
+	views, _ := cache.Get("page:views")
+	fmt.Printf("page:views after 100 concurrent increments: %v\n", views)
+
+	if _, err := Increment(cache, "page:views", 0.5); err != nil {
+		fmt.Printf("Increment with the wrong type: %v\n", err)
+	}
+
+	cache.Set("lock:report", "worker-1")
+	fmt.Printf("CompareAndSwap(worker-2 -> worker-3): %t\n", cache.CompareAndSwap("lock:report", "worker-2", "worker-3"))
+	fmt.Printf("CompareAndSwap(worker-1 -> worker-2): %t\n", cache.CompareAndSwap("lock:report", "worker-1", "worker-2"))
+	fmt.Printf("CompareAndDelete(worker-1): %t\n", cache.CompareAndDelete("lock:report", "worker-1"))
+
+	actual, loaded := cache.GetOrSet("config:theme", "dark")
+	fmt.Printf("GetOrSet(config:theme): %v loaded=%t\n", actual, loaded)
+	actual, loaded = cache.GetOrSet("config:theme", "light")
+	fmt.Printf("GetOrSet(config:theme): %v loaded=%t\n", actual, loaded)
+
+	recent, _ := cache.Update("recent:user:1", func(old interface{}, found bool) interface{} {
+		list, _ := old.([]string)
+		return append(list, "/home")
+	})
+	fmt.Printf("Update(recent:user:1): %v\n", recent)
+}
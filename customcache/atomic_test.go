@@ -0,0 +1,64 @@
+package customcache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCompareAndSwapNonComparableValues(t *testing.T) {
+	type profile struct {
+		Name  string
+		Roles []string
+	}
+	tests := []struct {
+		name            string
+		current, stale  interface{}
+		equal, newValue interface{}
+	}{
+		{"bytes", []byte("v1"), []byte("v0"), []byte("v1"), []byte("v2")},
+		{"map", map[string]int{"a": 1}, map[string]int{"a": 0}, map[string]int{"a": 1}, map[string]int{"a": 2}},
+		{"struct with a slice", profile{"a", []string{"x"}}, profile{"a", nil}, profile{"a", []string{"x"}}, profile{"b", nil}},
+		{"different types", []byte("1"), "1", []byte("1"), []byte("2")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewConcurrentCache()
+			defer cache.Close()
+			cache.Set("key", tt.current)
+
+			if cache.CompareAndSwap("key", tt.stale, tt.newValue) {
+				t.Fatal("swapped on a different value")
+			}
+			if !cache.CompareAndSwap("key", tt.equal, tt.newValue) {
+				t.Fatal("did not swap on an equal value")
+			}
+			if !cache.CompareAndDelete("key", tt.newValue) {
+				t.Fatal("did not delete on an equal value")
+			}
+		})
+	}
+}
+
+func TestIncrementWriteThrough(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Store: store, WriteMode: WriteThrough})
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Increment(cache, "counter", 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _ := cache.Get("counter"); value != 50 {
+		t.Fatalf("counter = %v after 50 increments", value)
+	}
+}
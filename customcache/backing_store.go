@@ -0,0 +1,128 @@
+package customcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The cache can front a persistent store. Reads that miss the cache are read through from the store,
+// and writes reach the store either synchronously (write-through) or in batches (write-behind).
+
+// ErrNotFound is returned by a BackingStore when the key does not exist
+var ErrNotFound = errors.New("customcache: key not found")
+
+// BackingStore is the persistent store behind the cache
+type BackingStore interface {
+	Load(ctx context.Context, key string) (interface{}, error) // Returns ErrNotFound for missing keys
+	Store(ctx context.Context, key string, value interface{}) error
+	Delete(ctx context.Context, key string) error // Deleting a missing key is not an error
+}
+
+// WriteMode controls how cache writes reach the BackingStore
+type WriteMode int
+
+const (
+	WriteThrough WriteMode = iota // Persist synchronously on every Set/Delete
+	WriteBehind                   // Queue writes and persist them from a background flusher
+)
+
+// FileStore is a reference BackingStore that keeps one gob-encoded file per key in a directory.
+// Values of custom types must be registered with gob.Register before they can be stored.
+type FileStore struct {
+	dir string
+}
+
+// fileRecord wraps the value so gob keeps its concrete type
+type fileRecord struct {
+	Value interface{}
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path maps a key to a file name; hex encoding keeps any key a valid file name
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, hex.EncodeToString([]byte(key)))
+}
+
+// Load reads the value stored for key
+func (s *FileStore) Load(ctx context.Context, key string) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record fileRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+		return nil, err
+	}
+	return record.Value, nil
+}
+
+// Store writes the value for key. The file is written to a temp file and renamed,
+// so readers never see a partially written value.
+func (s *FileStore) Store(ctx context.Context, key string, value interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(key), func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(fileRecord{Value: value})
+	})
+}
+
+// Delete removes the file for key
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// writeFileAtomic streams write's output to a temp file next to path and renames it over path,
+// so readers see either the old file or the complete new one
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once the rename succeeded
+
+	buf := bufio.NewWriter(tmp)
+	if err := write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,137 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ex/pkg/bloom"
+	"sync/atomic"
+)
+
+// Lookups of keys that exist nowhere miss the cache and each becomes a DB query. A BloomGuard
+// holds a counting Bloom filter of every key the source of truth has; wrapping a Loader with it
+// answers keys the filter has never seen with ErrNotFound without calling the loader at all.
+// Keep the filter in sync by calling Add when a key is created and Remove when it is deleted.
+
+// BloomGuardStats reports how well the guard is doing
+type BloomGuardStats struct {
+	Skipped        uint64  `json:"skipped"`         // Definite misses answered without the loader
+	Passed         uint64  `json:"passed"`          // Lookups the filter let through
+	FalsePositives uint64  `json:"false_positives"` // Let through, but the loader found nothing
+	ObservedFPR    float64 `json:"observed_fpr"`    // FalsePositives / all lookups of missing keys
+	EstimatedFPR   float64 `json:"estimated_fpr"`   // The filter's estimate from its fill ratio
+}
+
+func (s BloomGuardStats) String() string {
+	return fmt.Sprintf("skipped=%d passed=%d false_positives=%d observed_fpr=%.4f estimated_fpr=%.4f",
+		s.Skipped, s.Passed, s.FalsePositives, s.ObservedFPR, s.EstimatedFPR)
+}
+
+// BloomGuard puts a counting Bloom filter in front of a Loader
+type BloomGuard struct {
+	filter *bloom.CountingFilter
+
+	skipped        atomic.Uint64
+	passed         atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// NewBloomGuard creates a guard sized for expectedKeys keys at the target false-positive rate
+func NewBloomGuard(expectedKeys uint64, targetFPR float64) *BloomGuard {
+	return &BloomGuard{filter: bloom.NewWithEstimates(expectedKeys, targetFPR)}
+}
+
+// NewBloomGuardWithFilter creates a guard around an existing (e.g. deserialized or merged) filter
+func NewBloomGuardWithFilter(filter *bloom.CountingFilter) *BloomGuard {
+	return &BloomGuard{filter: filter}
+}
+
+// Filter returns the underlying filter, e.g. to serialize or merge it
+func (g *BloomGuard) Filter() *bloom.CountingFilter {
+	return g.filter
+}
+
+// Add records that key exists in the source of truth
+func (g *BloomGuard) Add(key string) {
+	g.filter.Add(key)
+}
+
+// Remove records that key was deleted from the source of truth
+func (g *BloomGuard) Remove(key string) {
+	g.filter.Remove(key)
+}
+
+// Loader wraps loader so keys the filter has never seen fail with ErrNotFound right away.
+// Use it with GetOrLoad; with NegativeTTL set, the misses are cached like any loader error.
+func (g *BloomGuard) Loader(loader Loader) Loader {
+	return func(ctx context.Context, key string) (interface{}, error) {
+		if !g.filter.Test(key) {
+			g.skipped.Add(1)
+			return nil, ErrNotFound
+		}
+		g.passed.Add(1)
+		value, err := loader(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			g.falsePositives.Add(1)
+		}
+		return value, err
+	}
+}
+
+// Stats returns the guard's counters and false-positive rates
+func (g *BloomGuard) Stats() BloomGuardStats {
+	s := BloomGuardStats{
+		Skipped:        g.skipped.Load(),
+		Passed:         g.passed.Load(),
+		FalsePositives: g.falsePositives.Load(),
+		EstimatedFPR:   g.filter.FalsePositiveRate(),
+	}
+	if missing := s.Skipped + s.FalsePositives; missing > 0 {
+		s.ObservedFPR = float64(s.FalsePositives) / float64(missing)
+	}
+	return s
+}
+
+func RunBloomGuard() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Quiet: true}) // Thousands of lookups follow
+	defer cache.Close()
+
+	// The "database" has 10,000 users; the guard is sized for them at a 1% false-positive rate
+	db := make(map[string]string)
+	guard := NewBloomGuard(10_000, 0.01)
+	for i := 0; i < 10_000; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		db[key] = fmt.Sprintf("User #%d", i)
+		guard.Add(key)
+	}
+	m, k := guard.Filter().Params()
+	fmt.Printf("Filter: %d counters (%d KB), %d hash functions\n", m, m>>10, k)
+
+	var queries atomic.Uint64
+	loadUser := guard.Loader(func(ctx context.Context, key string) (interface{}, error) {
+		queries.Add(1)
+		if value, ok := db[key]; ok {
+			return value, nil
+		}
+		return nil, ErrNotFound
+	})
+
+	// Mostly lookups of users that don't exist, e.g. from scrapers guessing IDs
+	for i := 0; i < 20_000; i++ {
+		cache.GetOrLoad(context.Background(), fmt.Sprintf("user:%d", i*7), loadUser)
+	}
+	fmt.Printf("DB queries for 20000 lookups: %d\n", queries.Load())
+	fmt.Printf("Guard: %s\n", guard.Stats())
+
+	// The filter survives a restart by serializing it
+	data, _ := guard.Filter().MarshalBinary()
+	restored := new(bloom.CountingFilter)
+	if err := restored.UnmarshalBinary(data); err != nil {
+		fmt.Printf("Restoring the filter failed: %v\n", err)
+		return
+	}
+	delete(db, "user:42")
+	restored.Remove("user:42")
+	fmt.Printf("Restored filter (%d bytes): user:1 maybe=%t user:42 maybe=%t\n",
+		len(data), restored.Test("user:1"), restored.Test("user:42"))
+}
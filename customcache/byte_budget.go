@@ -0,0 +1,167 @@
+package customcache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Counting entries means little when values range from 10 bytes to 10 MB, so the cache can be
+// given a MaxBytes budget instead. Every entry is sized with the configured Sizer when it is
+// stored; when a new entry doesn't fit, the least recently used entries are evicted until it does.
+// MaxEntries caps the number of entries the same way, and both limits can be combined.
+
+// Sizer returns the number of bytes an entry takes up
+type Sizer func(key string, value interface{}) int64
+
+// ErrTooLarge is returned when a value exceeds the per-entry limit of a byte-budgeted cache
+var ErrTooLarge = errors.New("customcache: value exceeds the per-entry size limit")
+
+// DefaultSizer counts the key plus the length of []byte and string values. Any other value counts
+// as 16 bytes (an interface header); set a Sizer for caches that hold other types.
+func DefaultSizer(key string, value interface{}) int64 {
+	size := int64(len(key))
+	switch v := value.(type) {
+	case []byte:
+		size += int64(len(v))
+	case string:
+		size += int64(len(v))
+	default:
+		size += 16
+	}
+	return size
+}
+
+// recency keeps the keys of a byte-budgeted cache in least recently used order
+type recency struct {
+	mu    sync.Mutex // Hits are recorded under c.mu's read lock, so the list has its own lock
+	order *list.List // Front is the most recently used key
+	elems map[string]*list.Element
+}
+
+func newRecency() *recency {
+	return &recency{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+// touch marks key as just used
+func (r *recency) touch(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.elems[key]; ok {
+		r.order.MoveToFront(elem)
+	}
+}
+
+// add inserts key as the most recently used one
+func (r *recency) add(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.elems[key]; ok {
+		r.order.MoveToFront(elem)
+		return
+	}
+	r.elems[key] = r.order.PushFront(key)
+}
+
+func (r *recency) remove(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.elems[key]; ok {
+		r.order.Remove(elem)
+		delete(r.elems, key)
+	}
+}
+
+// oldest returns the least recently used key other than skip
+func (r *recency) oldest(skip string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for elem := r.order.Back(); elem != nil; elem = elem.Prev() {
+		if key := elem.Value.(string); key != skip {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// entryLimit is the largest entry the cache accepts (0 means no limit)
+func (c *ConcurrentCache) entryLimit() int64 {
+	limit := c.config.MaxEntryBytes
+	if c.config.MaxBytes > 0 && (limit <= 0 || limit > c.config.MaxBytes) {
+		limit = c.config.MaxBytes // Nothing bigger than the whole budget can fit
+	}
+	return limit
+}
+
+// sized sets the item's size, or returns ErrTooLarge if it is over the per-entry limit
+func (c *ConcurrentCache) sized(key string, item Item) (Item, error) {
+	sizer := c.config.Sizer
+	if sizer == nil {
+		sizer = DefaultSizer
+	}
+	item.size = sizer(key, item.Value)
+	if limit := c.entryLimit(); limit > 0 && item.size > limit {
+		c.logf("Cache: Rejected key '%s' (%d bytes, limit %d)\n", key, item.size, limit)
+		return item, fmt.Errorf("%w: key %q is %d bytes, limit %d", ErrTooLarge, key, item.size, limit)
+	}
+	return item, nil
+}
+
+// makeRoom evicts least recently used entries until need more bytes (and one more entry, if
+// adding) fit in the limits. The key being written is never evicted. Called with c.mu held.
+func (c *ConcurrentCache) makeRoom(key string, need int64, adding bool) {
+	for c.overLimit(need, adding) {
+		victim, ok := c.lru.oldest(key)
+		if !ok {
+			return
+		}
+		old, _ := c.remove(victim)
+		c.logWrite(aofRecord{Op: aofDelete, Key: victim})
+		if old.expired(time.Now()) {
+			c.stats.Expire() // It was going to be dropped anyway
+			c.notify(EventExpire, victim, old.Value, nil)
+			continue
+		}
+		c.stats.Evict()
+		c.notify(EventEvict, victim, old.Value, nil)
+		c.logf("Cache: Evicted key '%s' (%d bytes)\n", victim, old.size)
+	}
+}
+
+// overLimit reports whether need more bytes and possibly one more entry would break MaxBytes or MaxEntries
+func (c *ConcurrentCache) overLimit(need int64, adding bool) bool {
+	entries := len(c.data)
+	if adding {
+		entries++
+	}
+	return (c.config.MaxBytes > 0 && c.bytes+need > c.config.MaxBytes) ||
+		(c.config.MaxEntries > 0 && entries > c.config.MaxEntries)
+}
+
+func RunByteBudget() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		MaxBytes:      1024,
+		MaxEntryBytes: 512,
+	})
+	defer cache.Close()
+
+	for i := 1; i <= 4; i++ {
+		cache.Set(fmt.Sprintf("thumb:%d", i), make([]byte, 200)) // ~207 bytes each
+	}
+	cache.Get("thumb:1") // thumb:1 is now the most recently used
+
+	// 400 more bytes need two evictions: thumb:2 and thumb:3 are the least recently used
+	cache.Set("page:home", strings.Repeat("x", 400))
+	for _, key := range []string{"thumb:1", "thumb:2", "thumb:3", "thumb:4", "page:home"} {
+		_, found := cache.Get(key)
+		fmt.Printf("%-9s found=%t\n", key, found)
+	}
+
+	if cas := cache.SetItem("video:1", Item{Value: make([]byte, 4096)}); cas == 0 {
+		fmt.Println("video:1 rejected: larger than MaxEntryBytes")
+	}
+	fmt.Printf("Stats: %s\n", cache.Stats())
+}
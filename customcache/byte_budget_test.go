@@ -0,0 +1,171 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// evictions collects the keys evicted from cache, in eviction order
+func evictions(t *testing.T, cache *ConcurrentCache) func() string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events := cache.Watch(ctx, "*")
+	return func() string {
+		var keys []string
+		for {
+			select {
+			case event := <-events:
+				if event.Type == EventEvict {
+					keys = append(keys, event.Key)
+				}
+			default:
+				return strings.Join(keys, " ")
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of cache joined in sorted order
+func sortedKeys(cache *ConcurrentCache) string {
+	keys := cache.Keys()
+	slices.Sort(keys)
+	return strings.Join(keys, " ")
+}
+
+func TestMaxBytesEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{MaxBytes: 30, Quiet: true})
+	defer cache.Close()
+	evicted := evictions(t, cache)
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, strings.Repeat("x", 9)) // 10 bytes with the key
+	}
+	cache.Get("a")  // a is now the most recently used
+	cache.Peek("b") // Peek doesn't mark b as used
+	cache.Set("d", strings.Repeat("x", 9))
+	if got := evicted(); got != "b" {
+		t.Fatalf("evicted %q to make room for d, want b", got)
+	}
+
+	// 20 bytes need two evictions, least recently used first
+	cache.Set("e", strings.Repeat("x", 19))
+	if got := evicted(); got != "c a" {
+		t.Fatalf("evicted %q to make room for e, want c a", got)
+	}
+	if got := sortedKeys(cache); got != "d e" {
+		t.Fatalf("Keys() = %q, want d e", got)
+	}
+
+	// Growing an entry evicts others, never the entry being written
+	cache.Set("e", strings.Repeat("x", 29))
+	if got := evicted(); got != "d" {
+		t.Fatalf("evicted %q to grow e, want d", got)
+	}
+	if stats := cache.Stats(); stats.Bytes != 30 || stats.Size != 1 || stats.Evictions != 4 {
+		t.Fatalf("Stats() = %s, want 30 bytes in 1 entry after 4 evictions", stats)
+	}
+}
+
+func TestMaxEntryBytesRejectsLargeValues(t *testing.T) {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{MaxBytes: 64, MaxEntryBytes: 16, Quiet: true})
+	defer cache.Close()
+
+	cache.Set("k", "small")
+	cas, err := cache.SetItemE("k", Item{Value: strings.Repeat("x", 16)}) // 17 bytes with the key
+	if cas != 0 || !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("SetItemE over MaxEntryBytes = %d, %v, want ErrTooLarge", cas, err)
+	}
+	if err := cache.SetE("k", strings.Repeat("x", 15)); err != nil {
+		t.Fatalf("SetE of exactly MaxEntryBytes: %v", err)
+	}
+	if err := cache.SetE("k", strings.Repeat("x", 100)); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("SetE over MaxEntryBytes = %v, want ErrTooLarge", err)
+	}
+	if value, _ := cache.Get("k"); value != strings.Repeat("x", 15) {
+		t.Fatalf("Get(k) = %v, a rejected write replaced the stored value", value)
+	}
+
+	// Without MaxEntryBytes nothing larger than MaxBytes is accepted either
+	budget := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{MaxBytes: 32, Quiet: true})
+	defer budget.Close()
+	budget.Set("a", "1")
+	if err := budget.SetE("big", strings.Repeat("x", 30)); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("SetE over MaxBytes = %v, want ErrTooLarge", err)
+	}
+	if _, ok := budget.Get("a"); !ok {
+		t.Fatal("a rejected value evicted a")
+	}
+}
+
+func TestStatsBytesAfterOverwriteAndDelete(t *testing.T) {
+	for _, config := range []ConcurrentCacheConfig{{Quiet: true}, {MaxBytes: 1 << 10, Quiet: true}} {
+		cache := NewConcurrentCacheWithConfig(config)
+		bytes := func() int64 { return cache.Stats().Bytes }
+
+		cache.Set("key", []byte("0123456789"))
+		cache.Set("other", "abc")
+		if got := bytes(); got != 13+8 {
+			t.Fatalf("MaxBytes %d: %d bytes after two sets, want 21", config.MaxBytes, got)
+		}
+		cache.Set("key", []byte("01"))
+		if got := bytes(); got != 5+8 {
+			t.Fatalf("MaxBytes %d: %d bytes after shrinking key, want 13", config.MaxBytes, got)
+		}
+		cache.Set("key", 42) // Not a string or []byte: an interface header
+		if got := bytes(); got != 19+8 {
+			t.Fatalf("MaxBytes %d: %d bytes after storing an int, want 27", config.MaxBytes, got)
+		}
+		cache.Delete("key")
+		cache.Delete("key")
+		if got := bytes(); got != 8 {
+			t.Fatalf("MaxBytes %d: %d bytes after Delete, want 8", config.MaxBytes, got)
+		}
+		cache.Purge()
+		if got := bytes(); got != 0 {
+			t.Fatalf("MaxBytes %d: %d bytes after Purge, want 0", config.MaxBytes, got)
+		}
+		cache.Close()
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{MaxEntries: 3, Quiet: true})
+	defer cache.Close()
+	evicted := evictions(t, cache)
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Set("a", 10) // Updating a key at the limit evicts nothing
+	if got := evicted(); got != "" {
+		t.Fatalf("evicted %q on an update", got)
+	}
+	cache.Get("b")
+	cache.Set("d", 4)
+	cache.Set("e", 5)
+	if got := evicted(); got != "c a" {
+		t.Fatalf("evicted %q, want c a", got)
+	}
+	if got := sortedKeys(cache); got != "b d e" {
+		t.Fatalf("Keys() = %q, want b d e", got)
+	}
+
+	// Both limits at once: whichever is hit first evicts
+	both := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{MaxEntries: 3, MaxBytes: 20, Quiet: true})
+	defer both.Close()
+	both.Set("a", "123456789")
+	both.Set("b", "123456789")
+	both.Set("c", "1") // 22 bytes: over MaxBytes with only 3 entries
+	if got := sortedKeys(both); got != "b c" {
+		t.Fatalf("Keys() = %q under both limits, want b c", got)
+	}
+	both.Set("d", "1")
+	both.Set("e", "1")
+	if got := sortedKeys(both); got != "c d e" {
+		t.Fatalf("Keys() = %q under both limits, want c d e", got)
+	}
+}
@@ -0,0 +1,90 @@
+package customcache
+
+import (
+	"fmt"
+	"go-ex/pkg/sharedresource"
+	"sync/atomic"
+	"testing"
+)
+
+// Benchmarks comparing the single-lock ConcurrentCache, the ShardedCache and the sync.Map based
+// sharedresource.ConcurrentCache under different read/write mixes:
+//
+//	go test -bench Mixed -cpu 1,4,8 ./customcache
+
+const benchmarkKeys = 4096
+
+// benchCache is the common surface of the caches being compared
+type benchCache interface {
+	set(key string, value interface{})
+	get(key string)
+}
+
+type concurrentBench struct{ c *ConcurrentCache }
+
+func (b concurrentBench) set(key string, value interface{}) { b.c.Set(key, value) }
+func (b concurrentBench) get(key string)                    { b.c.Get(key) }
+
+type shardedBench struct{ c *ShardedCache }
+
+func (b shardedBench) set(key string, value interface{}) { b.c.Set(key, value) }
+func (b shardedBench) get(key string)                    { b.c.Get(key) }
+
+type syncMapBench struct {
+	c *sharedresource.ConcurrentCache
+}
+
+func (b syncMapBench) set(key string, value interface{}) { b.c.Store(key, value) }
+func (b syncMapBench) get(key string)                    { b.c.Load(key) }
+
+// benchmarkMixed runs parallel Get/Set traffic where readPercent of the operations are reads
+func benchmarkMixed(b *testing.B, cache benchCache, readPercent int) {
+	keys := make([]string, benchmarkKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+	for _, key := range keys {
+		cache.set(key, key)
+	}
+
+	var seed atomic.Uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		x := seed.Add(1)*0x9E3779B97F4A7C15 | 1 // Per-goroutine xorshift state
+		for pb.Next() {
+			x ^= x << 13
+			x ^= x >> 7
+			x ^= x << 17
+			key := keys[x%benchmarkKeys]
+			if int(x>>32%100) < readPercent {
+				cache.get(key)
+			} else {
+				cache.set(key, key)
+			}
+		}
+	})
+}
+
+// benchmarkReadMixes runs benchmarkMixed at 90%, 50% and 10% reads, on a fresh cache each time
+func benchmarkReadMixes(b *testing.B, newCache func() benchCache) {
+	for _, readPercent := range []int{90, 50, 10} {
+		b.Run(fmt.Sprintf("reads=%d%%", readPercent), func(b *testing.B) {
+			benchmarkMixed(b, newCache(), readPercent)
+		})
+	}
+}
+
+func BenchmarkMixedConcurrentCache(b *testing.B) {
+	benchmarkReadMixes(b, func() benchCache {
+		return concurrentBench{NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Quiet: true})}
+	})
+}
+
+func BenchmarkMixedShardedCache(b *testing.B) {
+	benchmarkReadMixes(b, func() benchCache { return shardedBench{NewShardedCache(64, ConcurrentCacheConfig{Quiet: true})} })
+}
+
+func BenchmarkMixedSyncMap(b *testing.B) {
+	benchmarkReadMixes(b, func() benchCache { return syncMapBench{&sharedresource.ConcurrentCache{Quiet: true}} })
+}
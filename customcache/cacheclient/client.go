@@ -0,0 +1,157 @@
+package cacheclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ex/customcache/httpapi"
+	"go-ex/pkg/cachestats"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a typed Go client for the cache's HTTP API (see httpapi.NewHandler),
+// so other services can use the cache without hand-written requests.
+
+// ErrNotFound is returned when the key does not exist
+var ErrNotFound = errors.New("cacheclient: key not found")
+
+// Client talks to one cache service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// New creates a Client for the service at baseURL (e.g. "http://127.0.0.1:8080").
+// A nil httpClient uses http.DefaultClient.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Get returns the raw value stored under key
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, keyPath(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// GetJSON decodes the value stored under key into v
+func (c *Client) GetJSON(ctx context.Context, key string, v interface{}) error {
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Put stores raw bytes under key with an optional TTL (0 never expires)
+func (c *Client) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.put(ctx, key, value, "application/octet-stream", ttl)
+}
+
+// PutJSON stores v encoded as JSON under key with an optional TTL (0 never expires)
+func (c *Client) PutJSON(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.put(ctx, key, data, "application/json", ttl)
+}
+
+// Delete removes key. It returns ErrNotFound if the key did not exist.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, keyPath(key), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Bulk sets, deletes and gets many keys in a single round trip
+func (c *Client) Bulk(ctx context.Context, req httpapi.BulkRequest) (httpapi.BulkResponse, error) {
+	var out httpapi.BulkResponse
+	body, err := json.Marshal(req)
+	if err != nil {
+		return out, err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	resp, err := c.do(ctx, http.MethodPost, "/bulk", bytes.NewReader(body), header)
+	if err != nil {
+		return out, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&out)
+	return out, err
+}
+
+// Stats returns the service's cache statistics
+func (c *Client) Stats(ctx context.Context) (cachestats.Stats, error) {
+	var stats cachestats.Stats
+	resp, err := c.do(ctx, http.MethodGet, "/stats", nil, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	return stats, err
+}
+
+func (c *Client) put(ctx context.Context, key string, value []byte, contentType string, ttl time.Duration) error {
+	header := http.Header{"Content-Type": {contentType}}
+	if ttl > 0 {
+		seconds := int64((ttl + time.Second - 1) / time.Second) // The API works in whole seconds
+		header.Set(httpapi.TTLHeader, strconv.FormatInt(seconds, 10))
+	}
+	resp, err := c.do(ctx, http.MethodPut, keyPath(key), bytes.NewReader(value), header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a request and turns non-2xx replies into errors
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/keys/") {
+		return nil, ErrNotFound
+	}
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&apiErr)
+	return nil, fmt.Errorf("cacheclient: %s %s: %s: %s", method, path, resp.Status, apiErr.Error)
+}
+
+// keyPath escapes key so any string (including "/") is a single path value
+func keyPath(key string) string {
+	return "/keys/" + url.PathEscape(key)
+}
@@ -0,0 +1,93 @@
+package cacheclient
+
+import (
+	"context"
+	"errors"
+	"go-ex/customcache"
+	"go-ex/customcache/httpapi"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startService serves a fresh cache over the HTTP API and returns a client for it
+func startService(t *testing.T, config customcache.ConcurrentCacheConfig) (*Client, *customcache.ConcurrentCache) {
+	t.Helper()
+	cache := customcache.NewConcurrentCacheWithConfig(config)
+	server := httptest.NewServer(httpapi.NewHandler(cache))
+	t.Cleanup(func() {
+		server.Close()
+		cache.Close()
+	})
+	return New(server.URL+"/", server.Client()), cache
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	client, cache := startService(t, customcache.ConcurrentCacheConfig{})
+	ctx := context.Background()
+
+	if err := client.Put(ctx, "a/b c", []byte("value"), 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.Get(ctx, "a/b c"); err != nil || string(value) != "value" {
+		t.Fatalf("Get = %q, %v", value, err)
+	}
+	// The API works in whole seconds, so 1.5s is rounded up rather than down to 1s
+	if ttl, ok := cache.TTL("a/b c"); !ok || ttl <= 1500*time.Millisecond || ttl > 2*time.Second {
+		t.Fatalf("TTL = %v, %t, want 2s", ttl, ok)
+	}
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	if err := client.PutJSON(ctx, "user", user{"gopher"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	var got user
+	if err := client.GetJSON(ctx, "user", &got); err != nil || got.Name != "gopher" {
+		t.Fatalf("GetJSON = %+v, %v", got, err)
+	}
+
+	if err := client.Delete(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ctx, "user"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a deleted key: %v, want ErrNotFound", err)
+	}
+	if err := client.Delete(ctx, "user"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete of a missing key: %v, want ErrNotFound", err)
+	}
+
+	stats, err := client.Stats(ctx)
+	if err != nil || stats.Size != 1 || stats.Hits != 2 {
+		t.Fatalf("Stats = %+v, %v", stats, err)
+	}
+}
+
+func TestClientBulk(t *testing.T) {
+	client, _ := startService(t, customcache.ConcurrentCacheConfig{})
+
+	resp, err := client.Bulk(context.Background(), httpapi.BulkRequest{
+		Set: []httpapi.BulkEntry{{Key: "a", Bytes: []byte("1")}, {Key: "b", JSON: []byte(`true`)}},
+		Get: []string{"a", "b", "c"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Found) != 2 || string(resp.Found[0].Bytes) != "1" || string(resp.Found[1].JSON) != "true" {
+		t.Fatalf("found = %+v", resp.Found)
+	}
+	if len(resp.Missing) != 1 || resp.Missing[0] != "c" {
+		t.Fatalf("missing = %v", resp.Missing)
+	}
+}
+
+func TestClientReportsAPIErrors(t *testing.T) {
+	client, _ := startService(t, customcache.ConcurrentCacheConfig{MaxEntryBytes: 8})
+
+	err := client.Put(context.Background(), "k", []byte(strings.Repeat("x", 64)), 0)
+	if err == nil || !strings.Contains(err.Error(), "413") || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("Put of an oversized value: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package cacheclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"time"
+)
+
+// Layer adapts a Client to customcache.Cache, so a remote cache service can be the L2 of a
+// customcache.TieredCache. Values are sent as raw bytes, so only []byte and string values can be
+// stored; Get always returns []byte. Errors count as misses and are reported to OnError.
+type Layer struct {
+	Client  *Client
+	TTL     time.Duration               // TTL of values written through the layer (0 never expires)
+	Timeout time.Duration               // Per-request timeout (default 2s)
+	OnError func(key string, err error) // Called when a request fails (default: print it)
+}
+
+var _ customcache.CheckedCache = (*Layer)(nil)
+
+// NewLayer creates a Layer that stores values in the service with the given TTL
+func NewLayer(client *Client, ttl time.Duration) *Layer {
+	return &Layer{Client: client, TTL: ttl}
+}
+
+func (l *Layer) requestContext() (context.Context, context.CancelFunc) {
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = time.Second * 2
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func (l *Layer) failed(key string, err error) {
+	if l.OnError != nil {
+		l.OnError(key, err)
+		return
+	}
+	fmt.Printf("cacheclient: layer request for key '%s' failed: %v\n", key, err)
+}
+
+// Get returns the raw value stored under key
+func (l *Layer) Get(key string) (interface{}, bool) {
+	ctx, cancel := l.requestContext()
+	defer cancel()
+	value, err := l.Client.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			l.failed(key, err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores a []byte or string value under key
+func (l *Layer) Set(key string, value interface{}) {
+	l.SetE(key, value)
+}
+
+// SetE is Set that also returns the error it reports to OnError, so a TieredCache
+// doesn't cache a value in L1 that never reached the service
+func (l *Layer) SetE(key string, value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		err := fmt.Errorf("unsupported value type %T", value)
+		l.failed(key, err)
+		return err
+	}
+	ctx, cancel := l.requestContext()
+	defer cancel()
+	if err := l.Client.Put(ctx, key, data, l.TTL); err != nil {
+		l.failed(key, err)
+		return err
+	}
+	return nil
+}
+
+// Delete removes key from the service
+func (l *Layer) Delete(key string) {
+	ctx, cancel := l.requestContext()
+	defer cancel()
+	if err := l.Client.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+		l.failed(key, err)
+	}
+}
@@ -0,0 +1,76 @@
+package cacheclient
+
+import (
+	"errors"
+	"go-ex/customcache"
+	"testing"
+	"time"
+)
+
+func TestLayer(t *testing.T) {
+	client, cache := startService(t, customcache.ConcurrentCacheConfig{})
+	var failures []string
+	layer := NewLayer(client, time.Minute)
+	layer.OnError = func(key string, err error) { failures = append(failures, key) }
+
+	layer.Set("s", "string value")
+	layer.Set("b", []byte("byte value"))
+	for key, want := range map[string]string{"s": "string value", "b": "byte value"} {
+		value, ok := layer.Get(key)
+		if !ok || string(value.([]byte)) != want {
+			t.Fatalf("Get(%s) = %v, %t", key, value, ok)
+		}
+	}
+	if ttl, ok := cache.TTL("s"); !ok || ttl <= 0 {
+		t.Fatalf("TTL(s) = %v, %t, want the layer's TTL", ttl, ok)
+	}
+
+	layer.Delete("s")
+	layer.Delete("never-set") // A missing key is not a failure
+	if _, ok := layer.Get("s"); ok {
+		t.Fatal("deleted key s is still served")
+	}
+	if len(failures) != 0 {
+		t.Fatalf("failures for %v", failures)
+	}
+
+	layer.Set("n", 42)
+	if len(failures) != 1 || failures[0] != "n" {
+		t.Fatalf("failures = %v, want the unsupported value of n", failures)
+	}
+	if err := layer.SetE("n", 42); err == nil {
+		t.Fatal("SetE of an unsupported value returned no error")
+	}
+}
+
+func TestTieredCacheOverLayerKeepsRejectedValuesOutOfL1(t *testing.T) {
+	client, _ := startService(t, customcache.ConcurrentCacheConfig{})
+	layer := NewLayer(client, 0)
+	layer.OnError = func(string, error) {}
+	cache := customcache.NewTieredCache(layer, customcache.TieredCacheConfig{})
+	defer cache.Close()
+
+	if err := cache.SetE("s", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetE("n", 42); err == nil {
+		t.Fatal("SetE of a value the layer can't send returned no error")
+	}
+	if value, ok := cache.Get("n"); ok {
+		t.Fatalf("Get(n) = %v from L1, although the service never stored it", value)
+	}
+	if value, ok := cache.Get("s"); !ok || value != "value" {
+		t.Fatalf("Get(s) = %v, %t", value, ok)
+	}
+}
+
+func TestLayerCountsUnreachableServiceAsMiss(t *testing.T) {
+	var failed error
+	layer := NewLayer(New("http://127.0.0.1:1", nil), 0)
+	layer.Timeout = time.Second
+	layer.OnError = func(key string, err error) { failed = err }
+
+	if _, ok := layer.Get("k"); ok || failed == nil || errors.Is(failed, ErrNotFound) {
+		t.Fatalf("Get from an unreachable service: found=%t err=%v", ok, failed)
+	}
+}
@@ -1,49 +1,397 @@
 package customcache
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"go-ex/pkg/cachestats"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time" // Added for simulating concurrent access
 )
 
 // To make the cache thread-safe, we need to protect the shared map from concurrent access using a synchronization mechanism.
 
+// ConcurrentCacheConfig holds the optional cache settings
+type ConcurrentCacheConfig struct {
+	Quiet           bool          // Don't print every cache operation (they are printed by default)
+	CleanupInterval time.Duration // How often expired items are swept (0 means they are only removed on access)
+	DefaultTTL      time.Duration // TTL given to values added by Set, GetOrLoad and read-through (0 never expires)
+	NegativeTTL     time.Duration // How long loader errors are remembered by GetOrLoad (0 disables negative caching)
+	SoftTTL         time.Duration // GetOrLoad: after this, serve the value stale and reload it in the background (0 disables; must be below HardTTL)
+	HardTTL         time.Duration // GetOrLoad: loaded values are dropped after this (default DefaultTTL)
+	RefreshAhead    time.Duration // GetOrLoad: reload values read within this long before SoftTTL
+
+	Store          BackingStore                // Optional persistent store the cache fronts
+	WriteMode      WriteMode                   // How writes reach Store (WriteThrough or WriteBehind)
+	FlushInterval  time.Duration               // Write-behind: how often pending writes are flushed
+	FlushBatchSize int                         // Write-behind: flush early once this many keys are pending
+	MaxRetries     int                         // Write-behind: retries per key before giving up
+	RetryBackoff   time.Duration               // Write-behind: wait before the first retry, doubled each time
+	OnStoreError   func(key string, err error) // Called when a write could not be persisted
+
+	SnapshotPath     string        // Warm-start from this snapshot file and save to it on Close
+	SnapshotInterval time.Duration // Also save a snapshot to SnapshotPath this often (0 disables)
+
+	AOFPath        string     // Log every write to this append-only file and replay it on startup
+	AOFSync        SyncPolicy // How often the log is fsynced
+	AOFRewriteSize int64      // Compact the log once it reaches this many bytes and has doubled (0 disables)
+
+	MaxBytes      int64 // Evict least recently used entries to stay under this many bytes (0 means no limit)
+	MaxEntries    int   // Evict least recently used entries to stay under this many entries (0 means no limit)
+	MaxEntryBytes int64 // Reject entries larger than this (0 means MaxBytes)
+	Sizer         Sizer // How entries are sized (default DefaultSizer)
+
+	WatchBuffer   int            // Default channel buffer of Watch (default 64)
+	WatchOverflow OverflowPolicy // Default policy when a watcher's buffer is full
+}
+
 // Cache stores key-value pairs
 type ConcurrentCache struct {
-	mu   sync.RWMutex // Using RWMutex for better performance with many reads
-	data map[string]interface{}
+	mu         sync.RWMutex // Using RWMutex for better performance with many reads
+	data       map[string]Item
+	keyIndex   prefixIndex                    // Radix tree of the keys in data, for DeletePrefix
+	tags       map[string]map[string]struct{} // Tag -> keys carrying it, for InvalidateTag
+	negative   map[string]negativeEntry       // Cached loader errors, kept apart from real values
+	bytes      int64                          // Total size of the entries in data
+	lru        *recency                       // Recency order for MaxBytes/MaxEntries eviction (nil without a limit)
+	loads      loadGroup                      // Coalesces concurrent loads of the same key
+	refreshing sync.Map                       // Keys with a background GetOrLoad refresh running
+	config     ConcurrentCacheConfig
+
+	writeMu    sync.Mutex   // Write-through: keeps the store and the map in the same order
+	behind     *writeBehind // Write-behind: pending writes and the background flusher
+	aof        *aofLog      // Append-only log, if AOFPath is set
+	watchers   map[*watcher]struct{}
+	storeLoads loadGroup // Coalesces concurrent read-throughs of the same key
+
+	stats     cachestats.Counters
+	cas       atomic.Uint64 // Source of the per-item CAS versions
+	stop      chan struct{} // Stops the cleanup routine
+	closeOnce sync.Once
 }
 
 // NewConcurrentCache creates a new instance of ConcurrentCache
 func NewConcurrentCache() *ConcurrentCache {
-	return &ConcurrentCache{
-		data: make(map[string]interface{}),
+	return NewConcurrentCacheWithConfig(ConcurrentCacheConfig{})
+}
+
+// NewConcurrentCacheWithConfig creates a new ConcurrentCache with the given settings.
+// If AOFPath is set but the log cannot be opened or replayed, the error is printed and the cache
+// runs without a log; use NewConcurrentCacheE to fail instead.
+func NewConcurrentCacheWithConfig(config ConcurrentCacheConfig) *ConcurrentCache {
+	c, _ := newConcurrentCache(config, false)
+	return c
+}
+
+// NewConcurrentCacheE creates a new ConcurrentCache with the given settings. It returns an error
+// instead of a cache if AOFPath is set but the log cannot be opened or replayed.
+func NewConcurrentCacheE(config ConcurrentCacheConfig) (*ConcurrentCache, error) {
+	return newConcurrentCache(config, true)
+}
+
+// newConcurrentCache builds the cache and starts its background routines. With strict set, a log
+// that cannot be opened is returned as an error before anything is started.
+func newConcurrentCache(config ConcurrentCacheConfig, strict bool) (*ConcurrentCache, error) {
+	config = validRefreshConfig(config)
+	c := &ConcurrentCache{
+		data:     make(map[string]Item),
+		tags:     make(map[string]map[string]struct{}),
+		negative: make(map[string]negativeEntry),
+		watchers: make(map[*watcher]struct{}),
+		config:   config,
+		stop:     make(chan struct{}),
+	}
+	if config.MaxBytes > 0 || config.MaxEntries > 0 {
+		c.lru = newRecency()
+	}
+	if config.SnapshotPath != "" {
+		err := c.LoadSnapshotFile(config.SnapshotPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Cache: warm start from %s failed: %v\n", config.SnapshotPath, err)
+		}
+	}
+	if config.AOFPath != "" {
+		// The log is replayed on top of the snapshot, since it holds the more recent writes
+		aof, replayed, err := openAOF(config.AOFPath, config.AOFSync, c.applyAOF)
+		switch {
+		case err != nil && strict:
+			return nil, fmt.Errorf("customcache: opening append-only log %s: %w", config.AOFPath, err)
+		case err != nil:
+			fmt.Printf("Cache: opening append-only log %s failed: %v\n", config.AOFPath, err)
+		default:
+			c.aof = aof
+			c.logf("Cache: replayed %d records from %s\n", replayed, config.AOFPath)
+			go c.aofRoutine() // Start the fsync and compaction routine
+		}
 	}
+	if config.SnapshotPath != "" && config.SnapshotInterval > 0 {
+		go c.snapshotRoutine() // Start the periodic snapshots
+	}
+	if config.CleanupInterval > 0 {
+		go c.cleanupRoutine() // Start the expiry sweeper
+	}
+	if config.Store != nil && config.WriteMode == WriteBehind {
+		c.behind = newWriteBehind(config)
+		go c.behind.flushRoutine() // Start the background flusher
+	}
+	return c, nil
 }
 
 // Set adds or updates a key-value pair in the cache
 func (c *ConcurrentCache) Set(key string, value interface{}) {
-	c.mu.Lock()         // Acquire a write lock
-	defer c.mu.Unlock() // Release the write lock
-	c.data[key] = value
-	fmt.Printf("Cache: Set key '%s'\n", key)
+	c.SetItem(key, Item{Value: value, ExpiresAt: expiresAt(c.config.DefaultTTL)})
+}
+
+// SetE is Set that returns why the value was not stored, as SetItemE does
+func (c *ConcurrentCache) SetE(key string, value interface{}) error {
+	_, err := c.SetItemE(key, Item{Value: value, ExpiresAt: expiresAt(c.config.DefaultTTL)})
+	return err
+}
+
+// SetItem adds or updates a key together with its metadata and returns the item's new CAS version.
+// It returns 0 if the write could not be persisted to the store or the item is over MaxEntryBytes.
+func (c *ConcurrentCache) SetItem(key string, item Item) uint64 {
+	cas, _ := c.SetItemE(key, item)
+	return cas
+}
+
+// SetItemE is SetItem that says why a write was refused: an error wrapping ErrTooLarge for items
+// over MaxEntryBytes, or the store's error if a write-through store rejected the value.
+func (c *ConcurrentCache) SetItemE(key string, item Item) (uint64, error) {
+	item, err := c.sized(key, item)
+	if err != nil {
+		return 0, err
+	}
+	if c.config.Store != nil && c.config.WriteMode == WriteThrough {
+		c.writeMu.Lock()
+		defer c.writeMu.Unlock()
+		// Persist first so the cache never holds a value the store rejected
+		if err := c.config.Store.Store(context.Background(), key, item.Value); err != nil {
+			c.storeFailed(key, err)
+			return 0, err
+		}
+	}
+
+	c.mu.Lock()                // Acquire a write lock
+	defer c.unlockAndSync(key) // Release the write lock, then fsync under SyncAlways
+	return c.storeLocked(key, item).CAS, nil
 }
 
-// Get retrieves a value from the cache
+// storeLocked puts item in the map and passes the write on to the log, write-behind queue and
+// watchers. It returns the item with its new CAS version. Called with c.mu held.
+func (c *ConcurrentCache) storeLocked(key string, item Item) Item {
+	item.CAS = c.cas.Add(1)
+	old, hadOld := c.put(key, item)
+	c.logWrite(aofRecord{Op: aofSet, Key: key, Value: item.Value, Flags: item.Flags, ExpiresAt: item.ExpiresAt, Tags: item.Tags})
+	delete(c.negative, key) // A real value replaces any remembered loader error
+	if c.behind != nil {
+		c.behind.enqueue(key, item.Value, false) // Queued under c.mu so pending writes keep the map's order
+	}
+	c.notify(EventSet, key, liveValue(old, hadOld), item.Value)
+	c.stats.Set()
+	c.logf("Cache: Set key '%s'\n", key)
+	return item
+}
+
+// Get retrieves a value from the cache. With a Store configured, a miss is read through from the store.
 func (c *ConcurrentCache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()         // Acquire a read lock
-	defer c.mu.RUnlock() // Release the read lock
-	value, found := c.data[key]
-	fmt.Printf("Cache: Get key '%s' - Found: %t\n", key, found)
-	return value, found
+	item, found := c.GetItem(key)
+	return item.Value, found
+}
+
+// GetItem retrieves a value from the cache together with its metadata
+func (c *ConcurrentCache) GetItem(key string) (Item, bool) {
+	c.mu.RLock() // Acquire a read lock
+	item, found := c.data[key]
+	c.mu.RUnlock() // Released before any store access, so slow stores don't block writers
+	if found && item.expired(time.Now()) {
+		c.expire(key)
+		item, found = Item{}, false
+	}
+	if found {
+		c.stats.Hit()
+		if c.lru != nil {
+			c.lru.touch(key)
+		}
+	} else {
+		c.stats.Miss()
+		if c.config.Store != nil {
+			item, found = c.readThrough(key)
+		}
+	}
+	c.logf("Cache: Get key '%s' - Found: %t\n", key, found)
+	return item, found
+}
+
+// Peek returns the cached item of key without counting a hit or miss, marking it as used or
+// reading through to the store. It is meant for existence checks around writes.
+func (c *ConcurrentCache) Peek(key string) (Item, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, found := c.data[key]
+	if !found || item.expired(time.Now()) {
+		return Item{}, false
+	}
+	return item, true
 }
 
 // Delete removes a key-value pair from the cache
 func (c *ConcurrentCache) Delete(key string) {
-	c.mu.Lock()         // Acquire a write lock
-	defer c.mu.Unlock() // Release the write lock
+	c.delete(key)
+}
+
+// Purge drops every entry from memory and returns how many unexpired entries were dropped.
+// Unlike Delete it leaves the Store alone: pending write-behind writes are still flushed, and
+// the next read of a purged key reads it through again.
+func (c *ConcurrentCache) Purge() int {
+	c.mu.Lock()
+	defer c.unlockAndSync("")
+	now := time.Now()
+	purged := 0
+	for key := range c.data {
+		old, _ := c.remove(key)
+		c.logWrite(aofRecord{Op: aofDelete, Key: key}) // The log mirrors memory, not the store
+		if !old.expired(now) {
+			purged++
+			c.stats.Delete()
+			c.notify(EventDelete, key, old.Value, nil)
+		}
+	}
+	clear(c.negative)
+	c.logf("Cache: Purged %d keys\n", purged)
+	return purged
+}
+
+// delete removes key and reports whether an unexpired entry was removed
+func (c *ConcurrentCache) delete(key string) bool {
+	if c.config.Store != nil && c.config.WriteMode == WriteThrough {
+		c.writeMu.Lock()
+		defer c.writeMu.Unlock()
+		if err := c.config.Store.Delete(context.Background(), key); err != nil {
+			c.storeFailed(key, err)
+			return false
+		}
+	}
+
+	c.mu.Lock()                // Acquire a write lock
+	defer c.unlockAndSync(key) // Release the write lock, then fsync under SyncAlways
+	return c.deleteLocked(key)
+}
+
+// deleteLocked removes key and passes the delete on to the log, write-behind queue and watchers.
+// It reports whether an unexpired entry was removed. Called with c.mu held.
+func (c *ConcurrentCache) deleteLocked(key string) bool {
+	old, found := c.remove(key)
+	if found {
+		c.logWrite(aofRecord{Op: aofDelete, Key: key})
+		c.stats.Delete()
+		c.notify(EventDelete, key, liveValue(old, true), nil)
+	}
+	delete(c.negative, key)
+	if c.behind != nil {
+		c.behind.enqueue(key, nil, true)
+	}
+	c.logf("Cache: Deleted key '%s'\n", key)
+	return found && !old.expired(time.Now())
+}
+
+// put stores item under key and keeps the key, tag and byte accounting in step, evicting entries
+// if MaxBytes is set. It returns the previous item. Called with c.mu held.
+func (c *ConcurrentCache) put(key string, item Item) (Item, bool) {
+	if item.size == 0 { // Callers that can refuse an item have sized it already
+		var err error
+		if item, err = c.sized(key, item); err != nil {
+			return c.remove(key) // Too large to keep (a read-through or replayed value)
+		}
+	}
+	old, hadOld := c.data[key]
+	if c.lru != nil {
+		c.makeRoom(key, item.size-old.size, !hadOld) // old is the zero Item if there was none
+		c.lru.add(key)
+	}
+	if hadOld {
+		c.untag(key, old.Tags)
+	} else {
+		c.keyIndex.insert(key)
+	}
+	c.data[key] = item
+	c.bytes += item.size - old.size
+	c.tag(key, item.Tags)
+	return old, hadOld
+}
+
+// remove deletes key from the map and the indexes. Called with c.mu held.
+func (c *ConcurrentCache) remove(key string) (Item, bool) {
+	old, found := c.data[key]
+	if !found {
+		return Item{}, false
+	}
 	delete(c.data, key)
-	fmt.Printf("Cache: Deleted key '%s'\n", key)
+	c.keyIndex.remove(key)
+	c.untag(key, old.Tags)
+	c.bytes -= old.size
+	if c.lru != nil {
+		c.lru.remove(key)
+	}
+	return old, true
+}
+
+// Keys returns a snapshot of every key that has not expired, in no particular order
+func (c *ConcurrentCache) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	keys := make([]string, 0, len(c.data))
+	for key, item := range c.data {
+		if !item.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Stats returns a snapshot of the cache's hit/miss counters and current size
+func (c *ConcurrentCache) Stats() cachestats.Stats {
+	c.mu.RLock()
+	size, bytes := len(c.data), c.bytes
+	c.mu.RUnlock()
+	stats := c.stats.Snapshot(size)
+	stats.Bytes = bytes
+	return stats
+}
+
+// ResetStats zeroes the counters. Size and Bytes describe the contents, so they are unaffected.
+func (c *ConcurrentCache) ResetStats() {
+	c.stats.Reset()
+}
+
+// logf prints a cache operation unless Quiet is set
+func (c *ConcurrentCache) logf(format string, args ...interface{}) {
+	if !c.config.Quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// Close stops background work, flushes any pending write-behind writes to the store
+// and saves a final snapshot if SnapshotPath is set. With write-behind, writes made after
+// Close are written to the store synchronously.
+func (c *ConcurrentCache) Close() error {
+	var errs []error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		if c.behind != nil {
+			errs = append(errs, c.behind.close())
+		}
+		if c.config.SnapshotPath != "" {
+			errs = append(errs, c.SaveSnapshotFile(c.config.SnapshotPath))
+		}
+		if c.aof != nil {
+			errs = append(errs, c.aof.close())
+		}
+	})
+	return errors.Join(errs...)
 }
 
 func RunConcurrentCache() {
@@ -63,5 +411,6 @@ func RunConcurrentCache() {
 
 	wg.Wait()
 	fmt.Println("Concurrent access simulation finished.")
-	fmt.Printf("Final cache size: %d\n", len(cache.data)) // Note: len() on map requires lock
+	fmt.Printf("Final cache size: %d\n", cache.Stats().Size) // Stats reads len() under the lock
+	fmt.Printf("Stats: %s\n", cache.Stats())
 }
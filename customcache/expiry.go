@@ -0,0 +1,164 @@
+package customcache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Items can carry a TTL and small bits of client metadata (flags, CAS version),
+// which is what protocol front-ends like the memcached server map their entry fields to.
+// Expired items are dropped lazily on access and, if CleanupInterval is set, by a background sweep.
+
+// Item is a cached value together with its metadata
+type Item struct {
+	Value     interface{}
+	Flags     uint32    // Opaque client metadata, e.g. memcached flags
+	Tags      []string  // Groups the item can be invalidated by, see InvalidateTag
+	ExpiresAt time.Time // Zero means the item never expires
+	CAS       uint64    // Version of the item, changed by every write (ignored by SetItem)
+	size      int64     // Bytes counted against MaxBytes, set when the item is stored
+	refreshAt time.Time // GetOrLoad: serve stale and refresh from this point on (zero means never)
+}
+
+// expired reports whether the item's TTL has passed
+func (it Item) expired(now time.Time) bool {
+	return !it.ExpiresAt.IsZero() && !now.Before(it.ExpiresAt)
+}
+
+// liveValue returns the value of an item that was looked up in the map, or nil if it was
+// missing or already expired
+func liveValue(item Item, found bool) interface{} {
+	if !found || item.expired(time.Now()) {
+		return nil
+	}
+	return item.Value
+}
+
+// ToBytes returns the wire form of a cached value that may have been set through the Go API,
+// as sent by the protocol front-ends: []byte and string as is, anything else formatted with fmt
+func ToBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+// expiresAt turns a TTL into a deadline; a ttl <= 0 means no expiry
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// SetWithTTL adds or updates a key-value pair that expires after ttl (ttl <= 0 never expires)
+func (c *ConcurrentCache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.SetItem(key, Item{Value: value, ExpiresAt: expiresAt(ttl)})
+}
+
+// Touch sets a new TTL on an existing key without changing its value.
+// It reports whether the key was found.
+func (c *ConcurrentCache) Touch(key string, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlockAndSync(key)
+	item, found := c.data[key]
+	if !found || item.expired(time.Now()) {
+		return false
+	}
+	item.ExpiresAt = expiresAt(ttl)
+	c.data[key] = item
+	c.logWrite(aofRecord{Op: aofTouch, Key: key, ExpiresAt: item.ExpiresAt})
+	c.logf("Cache: Touched key '%s'\n", key)
+	return true
+}
+
+// TTL returns how long key has left to live. The bool is false if the key does not exist;
+// a zero duration with true means the key never expires.
+func (c *ConcurrentCache) TTL(key string) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, found := c.data[key]
+	now := time.Now()
+	if !found || item.expired(now) {
+		return 0, false
+	}
+	if item.ExpiresAt.IsZero() {
+		return 0, true
+	}
+	return item.ExpiresAt.Sub(now), true
+}
+
+// expire removes key if it is still expired once the write lock is held
+func (c *ConcurrentCache) expire(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, found := c.data[key]; found && item.expired(time.Now()) {
+		c.remove(key)
+		c.stats.Expire()
+		c.notify(EventExpire, key, item.Value, nil)
+	}
+}
+
+// deleteExpired removes every expired item and expired remembered loader error.
+// It returns the number of items removed.
+func (c *ConcurrentCache) deleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	removed := 0
+	for key, item := range c.data {
+		if item.expired(now) {
+			c.remove(key) // Deleting during range is fine for maps
+			c.stats.Expire()
+			c.notify(EventExpire, key, item.Value, nil)
+			removed++
+		}
+	}
+	for key, neg := range c.negative {
+		if !now.Before(neg.expiresAt) {
+			delete(c.negative, key) // Otherwise only dropped if the same key is loaded again
+			c.stats.Expire()
+		}
+	}
+	return removed
+}
+
+// cleanupRoutine periodically sweeps expired items until the cache is closed
+func (c *ConcurrentCache) cleanupRoutine() {
+	ticker := time.NewTicker(c.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if removed := c.deleteExpired(); removed > 0 {
+				c.logf("[Cleanup] Removed %d expired items\n", removed)
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func RunExpiringCache() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		CleanupInterval: time.Millisecond * 100,
+	})
+	defer cache.Close()
+
+	cache.SetWithTTL("session:1", "token-abc", time.Millisecond*200)
+	cache.Set("config:theme", "dark") // Never expires
+
+	if ttl, found := cache.TTL("session:1"); found {
+		fmt.Printf("session:1 expires in %v\n", ttl.Round(time.Millisecond))
+	}
+
+	time.Sleep(time.Millisecond * 300) // Let the session expire and the sweeper run
+	cache.Get("session:1")
+	cache.Get("config:theme")
+	fmt.Printf("Stats: %s\n", cache.Stats())
+}
@@ -0,0 +1,187 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The usual pattern is "check the cache, and on a miss query the DB and Set".
+// When a hot key expires every goroutine misses at once and they all hit the DB (thundering herd).
+// GetOrLoad coalesces concurrent misses for the same key so only one loader call runs.
+
+// Loader fetches the value for a key from the underlying data source on a cache miss
+type Loader func(ctx context.Context, key string) (interface{}, error)
+
+// negativeEntry is a remembered loader error for a key
+type negativeEntry struct {
+	err       error
+	expiresAt time.Time
+}
+
+// call is a single in-flight loader invocation shared by all waiters for a key
+type call struct {
+	done chan struct{} // Closed once val and err are set
+	val  interface{}
+	err  error
+}
+
+// loadGroup makes sure only one loader runs per key at a time (singleflight)
+type loadGroup struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// errLoaderPanicked is handed to waiters when the loader panicked in another goroutine
+var errLoaderPanicked = errors.New("customcache: loader panicked")
+
+// do runs fn for key unless a call for the same key is already in flight, in which case it
+// waits for that call's result. Waiters stop waiting when their own ctx is done; the caller that
+// started the call runs fn itself and so waits for it to return.
+func (g *loadGroup) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if cl, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-cl.done:
+			return cl.val, cl.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	cl := &call{done: make(chan struct{})}
+	g.calls[key] = cl
+	g.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			cl.err = errLoaderPanicked
+			g.finish(key, cl)
+			panic(r) // Re-panic in the goroutine that ran the loader
+		}
+		g.finish(key, cl)
+	}()
+	cl.val, cl.err = fn()
+	return cl.val, cl.err
+}
+
+// finish removes the call from the group and releases its waiters
+func (g *loadGroup) finish(key string, cl *call) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(cl.done)
+}
+
+// lookup returns the cached item or the remembered loader error for key
+func (c *ConcurrentCache) lookup(key string) (item Item, found bool, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if item, found := c.data[key]; found && !item.expired(time.Now()) {
+		if c.lru != nil {
+			c.lru.touch(key)
+		}
+		return item, true, nil
+	}
+	if neg, ok := c.negative[key]; ok && time.Now().Before(neg.expiresAt) {
+		return Item{}, false, neg.err
+	}
+	return Item{}, false, nil
+}
+
+// GetOrLoad returns the cached value for key. On a miss it calls loader and stores the result.
+// Concurrent misses for the same key share a single loader call, which gets a ctx without the
+// caller's cancellation so one caller giving up doesn't fail the load for the others.
+// If NegativeTTL is set, loader errors are remembered for that long and returned without calling
+// the loader again. They are never stored as values, so Get keeps reporting the key as missing.
+// Context errors (Canceled, DeadlineExceeded) are never remembered.
+// With SoftTTL set, values older than that are returned stale and refreshed in the background.
+func (c *ConcurrentCache) GetOrLoad(ctx context.Context, key string, loader Loader) (interface{}, error) {
+	if item, found, err := c.lookup(key); found || err != nil {
+		c.stats.Hit() // A remembered loader error is also served from the cache
+		c.logf("Cache: GetOrLoad key '%s' - Found: %t\n", key, found)
+		if found {
+			c.maybeRefresh(ctx, key, item, loader)
+		}
+		return item.Value, err
+	}
+	c.stats.Miss()
+
+	loadCtx := context.WithoutCancel(ctx) // Keeps ctx's values but not its deadline
+	return c.loads.do(ctx, key, func() (interface{}, error) {
+		// Another caller may have filled the key while we were waiting to become the leader
+		if item, found, err := c.lookup(key); found || err != nil {
+			return item.Value, err
+		}
+
+		c.expireNegative(key)
+		c.logf("Cache: GetOrLoad key '%s' - loading\n", key)
+		value, err := loader(loadCtx, key)
+		if err != nil {
+			if c.config.NegativeTTL > 0 && !isContextError(err) {
+				c.mu.Lock()
+				c.negative[key] = negativeEntry{err: err, expiresAt: time.Now().Add(c.config.NegativeTTL)}
+				c.mu.Unlock()
+			}
+			return nil, err
+		}
+		c.setLoaded(key, value)
+		return value, nil
+	})
+}
+
+// isContextError reports whether err comes from a cancelled or timed out context rather than the data source
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// expireNegative drops an expired loader error for key
+func (c *ConcurrentCache) expireNegative(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if neg, ok := c.negative[key]; ok && !time.Now().Before(neg.expiresAt) {
+		delete(c.negative, key)
+		c.stats.Expire()
+	}
+}
+
+func RunGetOrLoad() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{NegativeTTL: time.Second})
+	var dbQueries int32
+
+	// Simulated slow database lookup
+	loadUser := func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(&dbQueries, 1)
+		time.Sleep(time.Millisecond * 100)
+		if key == "user:missing" {
+			return nil, errors.New("user not found")
+		}
+		return fmt.Sprintf("value_for_%s", key), nil
+	}
+
+	// 10 goroutines miss on the same hot key at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetOrLoad(context.Background(), "user:1", loadUser)
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("DB queries for 10 concurrent misses: %d\n", atomic.LoadInt32(&dbQueries))
+
+	// A failing key is remembered for NegativeTTL instead of hitting the DB every time
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetOrLoad(context.Background(), "user:missing", loadUser)
+		fmt.Printf("GetOrLoad('user:missing') error: %v\n", err)
+	}
+	fmt.Printf("Total DB queries: %d\n", atomic.LoadInt32(&dbQueries))
+	fmt.Printf("Stats: %s\n", cache.Stats())
+}
@@ -0,0 +1,97 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadDoesNotRememberContextErrors(t *testing.T) {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{NegativeTTL: time.Minute})
+	defer cache.Close()
+
+	var calls atomic.Int32
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		if calls.Add(1) == 1 {
+			return nil, context.DeadlineExceeded // The data source timed out
+		}
+		return "value", nil
+	}
+	if _, err := cache.GetOrLoad(context.Background(), "key", loader); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("first GetOrLoad error = %v", err)
+	}
+	value, err := cache.GetOrLoad(context.Background(), "key", loader)
+	if err != nil || value != "value" {
+		t.Fatalf("second GetOrLoad = %v, %v; a timeout must not be remembered", value, err)
+	}
+}
+
+func TestGetOrLoadSurvivesLeaderCancellation(t *testing.T) {
+	cache := NewConcurrentCache()
+	defer cache.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error)
+	go func() {
+		_, err := cache.GetOrLoad(leaderCtx, "key", loader)
+		leaderDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan interface{})
+	go func() {
+		value, _ := cache.GetOrLoad(context.Background(), "key", loader)
+		waiterDone <- value
+	}()
+	cancel() // The caller that started the load gives up
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if err := <-leaderDone; err != nil {
+		t.Fatalf("leader error = %v", err)
+	}
+	if value := <-waiterDone; value != "value" {
+		t.Fatalf("waiter got %v, want the shared load's value", value)
+	}
+}
+
+func TestCleanupSweepsNegativeEntries(t *testing.T) {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		NegativeTTL:     10 * time.Millisecond,
+		CleanupInterval: 10 * time.Millisecond,
+	})
+	defer cache.Close()
+
+	failing := func(ctx context.Context, key string) (interface{}, error) { return nil, errors.New("not found") }
+	for _, key := range []string{"a", "b", "c"} {
+		cache.GetOrLoad(context.Background(), key, failing)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		cache.mu.RLock()
+		left := len(cache.negative)
+		cache.mu.RUnlock()
+		if left == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d expired loader errors were never swept", left)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
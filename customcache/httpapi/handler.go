@@ -0,0 +1,271 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// An HTTP REST front-end for customcache.ConcurrentCache:
+//
+//	GET    /keys/{key}  value as the response body, TTL in the X-Cache-TTL header
+//	PUT    /keys/{key}  store the request body, TTL from the X-Cache-TTL header (seconds)
+//	DELETE /keys/{key}  remove the key
+//	POST   /bulk        set, delete and get many keys in one request
+//	GET    /stats       cache statistics as JSON
+//
+// Values are stored as []byte. A value PUT with Content-Type application/json is validated and
+// marked with FlagJSON so it is served back as JSON; anything else is raw bytes.
+
+const (
+	// TTLHeader carries an entry's TTL in whole seconds. It is omitted for entries that never expire.
+	TTLHeader = "X-Cache-TTL"
+
+	// FlagJSON marks Item.Flags of values stored as JSON
+	FlagJSON uint32 = 1
+
+	maxValueBytes = 16 << 20
+)
+
+// BulkEntry is a single key in a bulk request or response.
+// Exactly one of JSON or Bytes holds the value; Bytes is base64 encoded on the wire.
+type BulkEntry struct {
+	Key        string          `json:"key"`
+	JSON       json.RawMessage `json:"json,omitempty"`
+	Bytes      []byte          `json:"bytes,omitempty"`
+	TTLSeconds int64           `json:"ttl_seconds,omitempty"`
+}
+
+// BulkRequest is the body of POST /bulk. Sets are applied first, then deletes, then gets.
+type BulkRequest struct {
+	Set    []BulkEntry `json:"set,omitempty"`
+	Delete []string    `json:"delete,omitempty"`
+	Get    []string    `json:"get,omitempty"`
+}
+
+// BulkResponse is the reply to POST /bulk
+type BulkResponse struct {
+	Found   []BulkEntry `json:"found"`
+	Missing []string    `json:"missing"`
+	Deleted int         `json:"deleted"`
+}
+
+// errorResponse is the JSON body of every non-2xx reply
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// handler serves the REST API for one cache
+type handler struct {
+	cache *customcache.ConcurrentCache
+}
+
+// NewHandler returns an http.Handler exposing cache over HTTP
+func NewHandler(cache *customcache.ConcurrentCache) http.Handler {
+	h := &handler{cache: cache}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys/{key...}", h.getKey)
+	mux.HandleFunc("PUT /keys/{key...}", h.putKey)
+	mux.HandleFunc("DELETE /keys/{key...}", h.deleteKey)
+	mux.HandleFunc("POST /bulk", h.bulk)
+	mux.HandleFunc("GET /stats", h.stats)
+	return mux
+}
+
+// pathKey returns the {key} of the request, replying 400 if it is empty (a request for /keys/)
+func pathKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.PathValue("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, "missing key")
+		return "", false
+	}
+	return key, true
+}
+
+// getKey serves GET /keys/{key}
+func (h *handler) getKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	item, found := h.cache.GetItem(key)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("key %q not found", key))
+		return
+	}
+
+	if item.Flags&FlagJSON != 0 {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	if ttl := ttlSeconds(item); ttl > 0 {
+		w.Header().Set(TTLHeader, strconv.FormatInt(ttl, 10))
+	}
+	w.Write(customcache.ToBytes(item.Value))
+}
+
+// putKey serves PUT /keys/{key}
+func (h *handler) putKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	ttl, err := parseTTL(r.Header.Get(TTLHeader))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValueBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "value too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item := customcache.Item{Value: data, ExpiresAt: deadline(ttl)}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if !json.Valid(data) {
+			writeError(w, http.StatusBadRequest, "body is not valid JSON")
+			return
+		}
+		item.Flags = FlagJSON
+	}
+	if _, err := h.cache.SetItemE(key, item); err != nil {
+		writeSetError(w, key, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteKey serves DELETE /keys/{key}
+func (h *handler) deleteKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	if _, exists := h.cache.TTL(key); !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("key %q not found", key))
+		return
+	}
+	h.cache.Delete(key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bulk serves POST /bulk
+func (h *handler) bulk(w http.ResponseWriter, r *http.Request) {
+	var req BulkRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxValueBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid bulk request: "+err.Error())
+		return
+	}
+	for _, entry := range req.Set {
+		switch {
+		case entry.Key == "":
+			writeError(w, http.StatusBadRequest, "set entry without a key")
+			return
+		case entry.JSON != nil && entry.Bytes != nil:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("key %q has both json and bytes", entry.Key))
+			return
+		case entry.TTLSeconds < 0:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("key %q has a negative ttl_seconds", entry.Key))
+			return
+		}
+	}
+
+	resp := BulkResponse{Found: []BulkEntry{}, Missing: []string{}}
+	for _, entry := range req.Set {
+		item := customcache.Item{Value: entry.Bytes, ExpiresAt: deadline(time.Duration(entry.TTLSeconds) * time.Second)}
+		if entry.JSON != nil {
+			item.Value, item.Flags = []byte(entry.JSON), FlagJSON
+		}
+		if _, err := h.cache.SetItemE(entry.Key, item); err != nil {
+			writeSetError(w, entry.Key, err) // The sets before this one have already been applied
+			return
+		}
+	}
+	for _, key := range req.Delete {
+		if _, exists := h.cache.TTL(key); exists {
+			h.cache.Delete(key)
+			resp.Deleted++
+		}
+	}
+	for _, key := range req.Get {
+		item, found := h.cache.GetItem(key)
+		if !found {
+			resp.Missing = append(resp.Missing, key)
+			continue
+		}
+		entry := BulkEntry{Key: key, TTLSeconds: ttlSeconds(item)}
+		if item.Flags&FlagJSON != 0 {
+			entry.JSON = customcache.ToBytes(item.Value)
+		} else {
+			entry.Bytes = customcache.ToBytes(item.Value)
+		}
+		resp.Found = append(resp.Found, entry)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeSetError replies 413 for values over the cache's entry limit and 502 when the store failed
+func writeSetError(w http.ResponseWriter, key string, err error) {
+	if errors.Is(err, customcache.ErrTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("key %q: value too large", key))
+		return
+	}
+	writeError(w, http.StatusBadGateway, fmt.Sprintf("key %q could not be persisted: %v", key, err))
+}
+
+// stats serves GET /stats
+func (h *handler) stats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.cache.Stats())
+}
+
+// parseTTL reads the X-Cache-TTL header; an empty header means no expiry
+func parseTTL(header string) (time.Duration, error) {
+	if header == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid %s header %q", TTLHeader, header)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// ttlSeconds returns the whole seconds an item has left, rounded up, or 0 if it never expires
+func ttlSeconds(item customcache.Item) int64 {
+	if item.ExpiresAt.IsZero() {
+		return 0
+	}
+	left := time.Until(item.ExpiresAt)
+	return int64((left + time.Second - 1) / time.Second)
+}
+
+// deadline turns a TTL into an Item deadline; 0 means no expiry
+func deadline(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,188 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ex/customcache"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerRejectsBadRequests(t *testing.T) {
+	cache := customcache.NewConcurrentCacheWithConfig(customcache.ConcurrentCacheConfig{MaxEntryBytes: 16})
+	defer cache.Close()
+	handler := NewHandler(cache)
+
+	tests := []struct {
+		name, method, target, body string
+		want                       int
+	}{
+		{"get without a key", http.MethodGet, "/keys/", "", http.StatusBadRequest},
+		{"put without a key", http.MethodPut, "/keys/", "value", http.StatusBadRequest},
+		{"delete without a key", http.MethodDelete, "/keys/", "", http.StatusBadRequest},
+		{"bulk set without a key", http.MethodPost, "/bulk", `{"set":[{"bytes":"dmFsdWU="}]}`, http.StatusBadRequest},
+		{"bulk negative ttl", http.MethodPost, "/bulk", `{"set":[{"key":"k","bytes":"dmFsdWU=","ttl_seconds":-1}]}`, http.StatusBadRequest},
+		{"bulk value over the entry limit", http.MethodPost, "/bulk", `{"set":[{"key":"k","json":"` + strings.Repeat("x", 32) + `"}]}`, http.StatusRequestEntityTooLarge},
+		{"put over the entry limit", http.MethodPut, "/keys/k", strings.Repeat("x", 32), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if w.Code != tt.want {
+				t.Fatalf("%s %s = %d (%s), want %d", tt.method, tt.target, w.Code, strings.TrimSpace(w.Body.String()), tt.want)
+			}
+		})
+	}
+	if size := cache.Stats().Size; size != 0 {
+		t.Fatalf("rejected requests stored %d keys", size)
+	}
+}
+
+// send serves one request and returns the recorded response
+func send(handler http.Handler, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for name, values := range header {
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlerKeyRoundTrip(t *testing.T) {
+	cache := customcache.NewConcurrentCache()
+	defer cache.Close()
+	handler := NewHandler(cache)
+
+	if w := send(handler, http.MethodPut, "/keys/a/b", "raw value", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT = %d", w.Code)
+	}
+	w := send(handler, http.MethodGet, "/keys/a/b", "", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "raw value" {
+		t.Fatalf("GET = %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/octet-stream" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get(TTLHeader); got != "" {
+		t.Fatalf("%s = %q for a key that never expires", TTLHeader, got)
+	}
+
+	if w := send(handler, http.MethodDelete, "/keys/a/b", "", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", w.Code)
+	}
+	if w := send(handler, http.MethodGet, "/keys/a/b", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("GET after DELETE = %d", w.Code)
+	}
+	if w := send(handler, http.MethodDelete, "/keys/a/b", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("second DELETE = %d", w.Code)
+	}
+}
+
+func TestHandlerJSONValues(t *testing.T) {
+	cache := customcache.NewConcurrentCache()
+	defer cache.Close()
+	handler := NewHandler(cache)
+	jsonHeader := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+
+	if w := send(handler, http.MethodPut, "/keys/user", `{"name":"gopher"}`, jsonHeader); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT = %d", w.Code)
+	}
+	if item, _ := cache.Peek("user"); item.Flags&FlagJSON == 0 {
+		t.Fatal("JSON value stored without FlagJSON")
+	}
+	w := send(handler, http.MethodGet, "/keys/user", "", nil)
+	if got := w.Header().Get("Content-Type"); got != "application/json" || w.Body.String() != `{"name":"gopher"}` {
+		t.Fatalf("GET = %q with Content-Type %q", w.Body.String(), got)
+	}
+	if w := send(handler, http.MethodPut, "/keys/user", `{"name":`, jsonHeader); w.Code != http.StatusBadRequest {
+		t.Fatalf("PUT of invalid JSON = %d, want 400", w.Code)
+	}
+}
+
+func TestHandlerTTLHeader(t *testing.T) {
+	cache := customcache.NewConcurrentCache()
+	defer cache.Close()
+	handler := NewHandler(cache)
+
+	if w := send(handler, http.MethodPut, "/keys/k", "v", http.Header{TTLHeader: {"60"}}); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT = %d", w.Code)
+	}
+	if ttl, ok := cache.TTL("k"); !ok || ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatalf("TTL(k) = %v, %t, want about a minute", ttl, ok)
+	}
+	if got := send(handler, http.MethodGet, "/keys/k", "", nil).Header().Get(TTLHeader); got != "60" {
+		t.Fatalf("%s = %q, want 60", TTLHeader, got)
+	}
+	if w := send(handler, http.MethodPut, "/keys/k", "v", http.Header{TTLHeader: {"-1"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("PUT with a negative TTL = %d, want 400", w.Code)
+	}
+}
+
+func TestHandlerBulk(t *testing.T) {
+	cache := customcache.NewConcurrentCache()
+	defer cache.Close()
+	handler := NewHandler(cache)
+	cache.Set("old", []byte("x"))
+
+	body, _ := json.Marshal(BulkRequest{
+		Set: []BulkEntry{
+			{Key: "raw", Bytes: []byte("bytes"), TTLSeconds: 30},
+			{Key: "doc", JSON: json.RawMessage(`[1,2]`)},
+		},
+		Delete: []string{"old", "never"},
+		Get:    []string{"raw", "doc", "old"},
+	})
+	w := send(handler, http.MethodPost, "/bulk", string(body), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /bulk = %d: %s", w.Code, w.Body.String())
+	}
+	var resp BulkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Deleted != 1 || len(resp.Missing) != 1 || resp.Missing[0] != "old" || len(resp.Found) != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	raw, doc := resp.Found[0], resp.Found[1]
+	if string(raw.Bytes) != "bytes" || raw.TTLSeconds != 30 || raw.JSON != nil {
+		t.Fatalf("raw = %+v", raw)
+	}
+	if string(doc.JSON) != `[1,2]` || doc.TTLSeconds != 0 || doc.Bytes != nil {
+		t.Fatalf("doc = %+v", doc)
+	}
+}
+
+// failingStore rejects every write
+type failingStore struct{}
+
+func (failingStore) Load(ctx context.Context, key string) (interface{}, error) {
+	return nil, customcache.ErrNotFound
+}
+func (failingStore) Store(ctx context.Context, key string, value interface{}) error {
+	return errors.New("disk full")
+}
+func (failingStore) Delete(ctx context.Context, key string) error { return nil }
+
+func TestHandlerStoreFailure(t *testing.T) {
+	cache := customcache.NewConcurrentCacheWithConfig(customcache.ConcurrentCacheConfig{
+		Store:        failingStore{},
+		OnStoreError: func(string, error) {},
+	})
+	defer cache.Close()
+	handler := NewHandler(cache)
+
+	if w := send(handler, http.MethodPut, "/keys/k", "v", nil); w.Code != http.StatusBadGateway {
+		t.Fatalf("PUT = %d, want 502", w.Code)
+	}
+	if w := send(handler, http.MethodPost, "/bulk", `{"set":[{"key":"k","bytes":"dg=="}]}`, nil); w.Code != http.StatusBadGateway {
+		t.Fatalf("POST /bulk = %d, want 502", w.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"net/http"
+	"sync"
+)
+
+// A customcache.NamespacedCache is served with the same API as NewHandler, once per namespace:
+//
+//	/ns/{namespace}/keys/{key}, /ns/{namespace}/bulk, /ns/{namespace}/stats
+//	DELETE /ns/{namespace}  drop every key of the namespace from memory (a backing store keeps them)
+//	GET    /ns              stats of every namespace
+//
+// Requests outside /ns go to customcache.DefaultNamespace, so plain NewHandler clients keep working.
+// Only a write (PUT or POST) creates a namespace, and only within the cache's MaxNamespaces;
+// anything else sent to a namespace that doesn't exist gets a 404.
+
+// namespacedHandler routes requests to a per-namespace handler
+type namespacedHandler struct {
+	cache *customcache.NamespacedCache
+
+	mu       sync.Mutex
+	handlers map[string]http.Handler // Namespace -> NewHandler of its cache
+}
+
+// purgeResponse is the reply to DELETE /ns/{namespace}
+type purgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+// NewNamespacedHandler returns an http.Handler exposing every namespace of cache over HTTP
+func NewNamespacedHandler(cache *customcache.NamespacedCache) http.Handler {
+	h := &namespacedHandler{cache: cache, handlers: make(map[string]http.Handler)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ns", h.allStats)
+	mux.HandleFunc("DELETE /ns/{namespace}", h.purge)
+	mux.HandleFunc("/ns/{namespace}/{rest...}", h.forward)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		h.serveNamespace(customcache.DefaultNamespace, w, r)
+	})
+	return mux
+}
+
+// handlerFor returns the handler of namespace, creating the namespace first if create is set
+func (h *namespacedHandler) handlerFor(namespace string, create bool) (http.Handler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if handler, ok := h.handlers[namespace]; ok {
+		return handler, nil
+	}
+	cache, ok := h.cache.Lookup(namespace)
+	if !ok {
+		if !create {
+			return nil, errNoNamespace
+		}
+		var err error
+		if cache, err = h.cache.Namespace(namespace); err != nil {
+			return nil, err
+		}
+	}
+	handler := NewHandler(cache)
+	h.handlers[namespace] = handler
+	return handler, nil
+}
+
+// errNoNamespace is returned by handlerFor for a namespace that doesn't exist and wasn't created
+var errNoNamespace = errors.New("no such namespace")
+
+func (h *namespacedHandler) serveNamespace(namespace string, w http.ResponseWriter, r *http.Request) {
+	create := r.Method == http.MethodPut || r.Method == http.MethodPost
+	handler, err := h.handlerFor(namespace, create)
+	switch {
+	case errors.Is(err, errNoNamespace):
+		writeError(w, http.StatusNotFound, fmt.Sprintf("namespace %q not found", namespace))
+	case errors.Is(err, customcache.ErrNamespaceLimit):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, customcache.ErrClosed):
+		writeError(w, http.StatusServiceUnavailable, "cache is shutting down")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// forward strips /ns/{namespace} and passes the request on to the namespace's handler
+func (h *namespacedHandler) forward(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = "/" + r.PathValue("rest")
+	r2.URL.RawPath = ""
+	h.serveNamespace(namespace, w, r2)
+}
+
+func (h *namespacedHandler) purge(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, purgeResponse{Purged: h.cache.Purge(r.PathValue("namespace"))})
+}
+
+func (h *namespacedHandler) allStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.cache.AllStats())
+}
@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"go-ex/customcache"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNamespacedHandlerOnlyWritesCreateNamespaces(t *testing.T) {
+	cache := customcache.NewNamespacedCache(customcache.NamespacedCacheConfig{MaxNamespaces: 1})
+	defer cache.Close()
+	handler := NewNamespacedHandler(cache)
+
+	send := func(method, target string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader("value")))
+		return w.Code
+	}
+
+	for _, target := range []string{"/ns/a/keys/k", "/ns/a/stats"} {
+		if code := send(http.MethodGet, target); code != http.StatusNotFound {
+			t.Fatalf("GET %s = %d, want 404", target, code)
+		}
+	}
+	if code := send(http.MethodDelete, "/ns/a/keys/k"); code != http.StatusNotFound {
+		t.Fatalf("DELETE in an unknown namespace = %d, want 404", code)
+	}
+	if _, ok := cache.Lookup("a"); ok {
+		t.Fatal("a read created namespace a")
+	}
+
+	if code := send(http.MethodPut, "/ns/a/keys/k"); code != http.StatusNoContent {
+		t.Fatalf("PUT /ns/a/keys/k = %d", code)
+	}
+	if code := send(http.MethodGet, "/ns/a/keys/k"); code != http.StatusOK {
+		t.Fatalf("GET /ns/a/keys/k = %d after the PUT", code)
+	}
+	if code := send(http.MethodPut, "/ns/b/keys/k"); code != http.StatusForbidden {
+		t.Fatalf("PUT past MaxNamespaces = %d, want 403", code)
+	}
+	if code := send(http.MethodGet, "/stats"); code != http.StatusOK {
+		t.Fatalf("GET /stats of the default namespace = %d", code)
+	}
+	if got := strings.Join(cache.Namespaces(), ","); got != "a,default" {
+		t.Fatalf("Namespaces() = %s", got)
+	}
+}
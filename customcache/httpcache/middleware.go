@@ -0,0 +1,454 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go-ex/customcache"
+	"go-ex/pkg/cachestats"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Middleware is a shared HTTP cache in front of an origin handler, built on customcache.
+//
+// Only GET responses are cached, keyed by URL plus the request headers the response Varies on.
+// Freshness comes from the response's Cache-Control: s-maxage wins over max-age (this is a
+// shared cache), and no-store, no-cache and private responses are never stored. Responses
+// without an explicit lifetime aren't cached either. Every stored response has an ETag (the
+// origin's, or a hash of the body), so If-None-Match requests get a 304 from the cache.
+// With ServeStale set, a stale response is served when the origin fails with a 5xx.
+//
+// Origin responses are streamed to the client as they are written; a copy of the body is kept
+// for the cache until it passes MaxBodyBytes, after which the response is just passed through.
+
+// Config holds the middleware settings
+type Config struct {
+	MaxBytes     int64         // Cache size (default 64 MB)
+	MaxBodyBytes int64         // Larger responses are passed through but not stored (default 1 MB)
+	ServeStale   time.Duration // Serve responses up to this long past expiry if the origin fails (0 disables)
+}
+
+// Header names the middleware sets on its responses
+const (
+	StatusHeader = "X-Cache" // HIT, MISS, STALE or BYPASS
+	ageHeader    = "Age"
+)
+
+// entry is a stored response
+type entry struct {
+	status     int
+	header     http.Header
+	body       []byte
+	storedAt   time.Time
+	freshUntil time.Time
+}
+
+// Middleware caches the responses of next
+type Middleware struct {
+	next   http.Handler
+	cache  *customcache.ConcurrentCache
+	config Config
+
+	stale atomic.Uint64 // Stale responses served because the origin failed
+}
+
+// New wraps next with a response cache
+func New(next http.Handler, config Config) *Middleware {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 64 << 20
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 1 << 20
+	}
+	cache := customcache.NewConcurrentCacheWithConfig(customcache.ConcurrentCacheConfig{
+		MaxBytes:        config.MaxBytes,
+		Sizer:           sizeOf,
+		CleanupInterval: time.Minute,
+		Quiet:           true,
+	})
+	return &Middleware{next: next, cache: cache, config: config}
+}
+
+// sizeOf sizes entries and Vary lists for the byte budget
+func sizeOf(key string, value interface{}) int64 {
+	size := int64(len(key))
+	switch v := value.(type) {
+	case *entry:
+		size += int64(len(v.body)) + 64
+		for name, values := range v.header {
+			size += int64(len(name))
+			for _, value := range values {
+				size += int64(len(value))
+			}
+		}
+	case []string:
+		for _, name := range v {
+			size += int64(len(name))
+		}
+	}
+	return size
+}
+
+// cacheControl holds the parsed directives of a Cache-Control header, lowercased
+type cacheControl map[string]string
+
+func parseCacheControl(header string) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(directive, "=")
+		cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns a delta-seconds directive
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// baseKey identifies a resource before Vary is applied. The Host is part of it, since one
+// middleware may sit in front of several virtual hosts.
+func baseKey(r *http.Request) string {
+	return r.Method + " " + r.Host + r.URL.RequestURI()
+}
+
+// variantKey adds the values of the Vary request headers to the base key
+func variantKey(base string, vary []string, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(base)
+	for _, name := range vary {
+		b.WriteString("\n")
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// varyNames returns the canonical, sorted header names of a Vary response header
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the stored response for r, fresh or not
+func (m *Middleware) lookup(r *http.Request) (*entry, bool) {
+	base := baseKey(r)
+	vary, ok := m.cache.Get("vary:" + base)
+	if !ok {
+		return nil, false
+	}
+	value, ok := m.cache.Get("resp:" + variantKey(base, vary.([]string), r))
+	if !ok {
+		return nil, false
+	}
+	return value.(*entry), true
+}
+
+func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+	if r.Method != http.MethodGet || reqCC.has("no-store") {
+		w.Header().Set(StatusHeader, "BYPASS")
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	now := time.Now()
+	cached, found := m.lookup(r)
+	revalidate := reqCC.has("no-cache") || reqCC["max-age"] == "0"
+	if found && !revalidate && now.Before(cached.freshUntil) {
+		m.serve(w, r, cached, "HIT", now)
+		return
+	}
+
+	// Ask the origin, streaming its response to the client and keeping a copy to store
+	tee := &teeWriter{
+		w:        w,
+		header:   make(http.Header),
+		limit:    m.config.MaxBodyBytes,
+		canStale: found && now.Before(cached.freshUntil.Add(m.config.ServeStale)),
+	}
+	m.next.ServeHTTP(tee, r)
+	if tee.status == 0 {
+		tee.WriteHeader(http.StatusOK) // The origin wrote nothing
+	}
+
+	if tee.discard {
+		m.stale.Add(1)
+		m.serve(w, r, cached, "STALE", now)
+		return
+	}
+	if !tee.overflow {
+		m.store(r, &entry{status: tee.status, header: tee.header.Clone(), body: tee.body.Bytes(), storedAt: now})
+	}
+}
+
+// teeWriter passes the origin's response on to the client while keeping a copy of up to limit
+// bytes of the body for the cache. If the origin fails and a stale copy can be served instead,
+// the origin's response is swallowed.
+type teeWriter struct {
+	w        http.ResponseWriter
+	header   http.Header // The origin's headers, copied to w once the status is known
+	limit    int64
+	canStale bool // A stale response may replace a 5xx
+
+	status   int // 0 until the origin writes the header
+	body     bytes.Buffer
+	overflow bool // The body passed limit and is not kept
+	discard  bool // The origin failed; the stale response is served instead
+}
+
+func (t *teeWriter) Header() http.Header {
+	return t.header
+}
+
+func (t *teeWriter) WriteHeader(status int) {
+	if t.status != 0 {
+		return
+	}
+	t.status = status
+	if status >= 500 && t.canStale {
+		t.discard = true
+		return
+	}
+	header := t.w.Header()
+	for name, values := range t.header {
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set(StatusHeader, "MISS")
+	t.w.WriteHeader(status)
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	if t.status == 0 {
+		t.WriteHeader(http.StatusOK)
+	}
+	if !t.overflow {
+		if int64(t.body.Len()+len(p)) > t.limit {
+			t.overflow = true
+			t.body = bytes.Buffer{} // Too big to store; stop copying
+		} else {
+			t.body.Write(p)
+		}
+	}
+	if t.discard {
+		return len(p), nil
+	}
+	return t.w.Write(p)
+}
+
+// Flush sends what the origin has written so far, for streaming responses
+func (t *teeWriter) Flush() {
+	if t.status == 0 {
+		t.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := t.w.(http.Flusher); ok && !t.discard {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the client's ResponseWriter
+func (t *teeWriter) Unwrap() http.ResponseWriter {
+	return t.w
+}
+
+// store saves resp if its Cache-Control allows a shared cache to
+func (m *Middleware) store(r *http.Request, resp *entry) {
+	cc := parseCacheControl(resp.header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("no-cache") || cc.has("private") || resp.header.Get("Set-Cookie") != "" {
+		return
+	}
+	if !cacheableStatus(resp.status) || int64(len(resp.body)) > m.config.MaxBodyBytes {
+		return
+	}
+	ttl, ok := cc.seconds("s-maxage")
+	if !ok {
+		ttl, ok = cc.seconds("max-age")
+	}
+	if !ok || ttl <= 0 {
+		return // No explicit lifetime
+	}
+	// Responses to authenticated requests are per-user unless the origin says otherwise
+	if r.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") {
+		return
+	}
+	vary := varyNames(resp.header)
+	for _, name := range vary {
+		if name == "*" {
+			return // Varies on things we can't see
+		}
+	}
+
+	if resp.header.Get("ETag") == "" {
+		sum := sha256.Sum256(resp.body)
+		resp.header.Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+	}
+	resp.freshUntil = resp.storedAt.Add(ttl)
+
+	// Keep stale copies around for ServeStale; the Vary list is refreshed with every stored variant
+	keep := ttl + m.config.ServeStale
+	base := baseKey(r)
+	m.cache.SetWithTTL("vary:"+base, vary, keep)
+	m.cache.SetWithTTL("resp:"+variantKey(base, vary, r), resp, keep)
+}
+
+// cacheableStatus reports whether responses with this status may be stored given an explicit lifetime
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// serve writes a stored response, or 304 if the client already has its ETag
+func (m *Middleware) serve(w http.ResponseWriter, r *http.Request, resp *entry, status string, now time.Time) {
+	header := w.Header()
+	for name, values := range resp.header {
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set(StatusHeader, status)
+	header.Set(ageHeader, strconv.Itoa(int(now.Sub(resp.storedAt).Seconds())))
+	if status == "STALE" {
+		header.Set("Warning", `110 - "Response is Stale"`)
+	}
+
+	if etag := resp.header.Get("ETag"); etag != "" && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// etagMatches implements the weak comparison If-None-Match uses
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the underlying cache's stats (Vary lists included) and the number of stale responses served
+func (m *Middleware) Stats() (cachestats.Stats, uint64) {
+	return m.cache.Stats(), m.stale.Load()
+}
+
+// Close stops the cache's background work
+func (m *Middleware) Close() error {
+	return m.cache.Close()
+}
+
+func RunHTTPCache() {
+	var originCalls atomic.Int32
+	var failing atomic.Bool
+	origin := http.NewServeMux()
+	origin.HandleFunc("GET /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		originCalls.Add(1)
+		if failing.Load() {
+			http.Error(w, "database down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=1")
+		w.Header().Set("Vary", "Accept-Language")
+		greeting := "Product"
+		if r.Header.Get("Accept-Language") == "de" {
+			greeting = "Produkt"
+		}
+		fmt.Fprintf(w, "%s %s", greeting, r.PathValue("id"))
+	})
+	origin.HandleFunc("GET /cart", func(w http.ResponseWriter, r *http.Request) {
+		originCalls.Add(1)
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		fmt.Fprint(w, "your cart")
+	})
+
+	cache := New(origin, Config{ServeStale: time.Minute})
+	defer cache.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Printf("Failed to listen: %v\n", err)
+		return
+	}
+	server := &http.Server{Handler: cache}
+	go server.Serve(listener)
+	defer server.Close()
+	base := "http://" + listener.Addr().String()
+
+	get := func(path, lang, etag string) string {
+		r, _ := http.NewRequest(http.MethodGet, base+path, nil)
+		if lang != "" {
+			r.Header.Set("Accept-Language", lang)
+		}
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			fmt.Printf("GET %s failed: %v\n", path, err)
+			return ""
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Printf("GET %-12s lang=%-2s -> %d %-5s %q\n", path, lang, resp.StatusCode, resp.Header.Get(StatusHeader), body)
+		return resp.Header.Get("ETag")
+	}
+
+	get("/products/1", "en", "")
+	etag := get("/products/1", "en", "") // Served from the cache, with the ETag it was stored under
+	get("/products/1", "de", "")         // Different Vary value, separate entry
+	get("/products/1", "en", etag)
+	get("/cart", "", "")
+	get("/cart", "", "") // private: never stored
+
+	time.Sleep(time.Millisecond * 1100) // Let max-age pass
+	failing.Store(true)
+	get("/products/1", "en", "")
+
+	stats, stale := cache.Stats()
+	fmt.Printf("Origin calls: %d, stale responses: %d\n", originCalls.Load(), stale)
+	fmt.Printf("Stats: %s\n", stats)
+}
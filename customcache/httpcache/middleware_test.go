@@ -0,0 +1,85 @@
+package httpcache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// do sends a GET for target through handler and returns the recorded response
+func do(handler http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestMiddlewareKeysOnHost(t *testing.T) {
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(w, r.Host)
+	})
+	cache := New(origin, Config{})
+	defer cache.Close()
+
+	for _, host := range []string{"a.example", "b.example", "a.example", "b.example"} {
+		w := do(cache, "http://"+host+"/page")
+		if body := w.Body.String(); body != host {
+			t.Fatalf("GET %s/page returned %q", host, body)
+		}
+	}
+}
+
+func TestMiddlewareStreamsLargeBodiesWithoutStoring(t *testing.T) {
+	var calls atomic.Int32
+	body := strings.Repeat("x", 100)
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		for i := 0; i < 10; i++ {
+			fmt.Fprint(w, body[:10])
+			w.(http.Flusher).Flush()
+		}
+	})
+	cache := New(origin, Config{MaxBodyBytes: 50})
+	defer cache.Close()
+
+	for i := 0; i < 2; i++ {
+		w := do(cache, "/big")
+		if w.Body.String() != body || !w.Flushed {
+			t.Fatalf("got %d bytes, flushed %t", w.Body.Len(), w.Flushed)
+		}
+		if status := w.Header().Get(StatusHeader); status != "MISS" {
+			t.Fatalf("%s = %s, want MISS", StatusHeader, status)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("origin called %d times, a body over MaxBodyBytes must not be stored", calls.Load())
+	}
+}
+
+func TestMiddlewareServesStaleInsteadOfOriginError(t *testing.T) {
+	var failing atomic.Bool
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "database down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=1")
+		fmt.Fprint(w, "fresh")
+	})
+	cache := New(origin, Config{ServeStale: time.Minute})
+	defer cache.Close()
+
+	do(cache, "/page")
+	time.Sleep(1100 * time.Millisecond)
+	failing.Store(true)
+
+	w := do(cache, "/page")
+	if w.Code != http.StatusOK || w.Body.String() != "fresh" || w.Header().Get(StatusHeader) != "STALE" {
+		t.Fatalf("got %d %s %q, want the stale response", w.Code, w.Header().Get(StatusHeader), w.Body.String())
+	}
+}
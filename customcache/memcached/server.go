@@ -0,0 +1,471 @@
+package memcached
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A memcached text protocol front-end for customcache.ConcurrentCache, so the cache can run as a
+// standalone sidecar that existing memcached clients talk to.
+// Supported commands: get, gets, set, add, replace, cas, delete, incr, decr, touch, stats, version, quit.
+// Values are stored as []byte; memcached flags and exptime map to Item.Flags and Item.ExpiresAt.
+
+const (
+	maxKeyLength       = 250
+	maxValueSize       = 1 << 20           // 1MB, the memcached default item size limit
+	maxLineLength      = 2048              // Longest command line accepted
+	maxRelativeExptime = 60 * 60 * 24 * 30 // Larger exptimes are absolute unix timestamps
+	version            = "1.6.0-customcache"
+)
+
+// errClientQuit ends the connection after a quit command
+var errClientQuit = errors.New("client quit")
+
+// Server serves the memcached text protocol on top of a ConcurrentCache
+type Server struct {
+	cache *customcache.ConcurrentCache
+	mu    sync.Mutex // Serializes write commands so add/replace/cas/incr/decr/touch are atomic
+
+	started          time.Time
+	currConnections  atomic.Int64
+	totalConnections atomic.Uint64
+	cmdGet           atomic.Uint64
+	cmdSet           atomic.Uint64
+	cmdTouch         atomic.Uint64
+
+	connMu   sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+// NewServer creates a memcached protocol server for cache
+func NewServer(cache *customcache.ConcurrentCache) *Server {
+	return &Server{
+		cache:   cache,
+		started: time.Now(),
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the TCP address addr and serves clients until Close is called
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and handles each one in its own goroutine
+func (s *Server) Serve(l net.Listener) error {
+	s.connMu.Lock()
+	if s.closed {
+		s.connMu.Unlock()
+		l.Close()
+		return net.ErrClosed
+	}
+	s.listener = l
+	s.connMu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.connMu.Lock()
+			closed := s.closed
+			s.connMu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.connMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.connMu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+// Close stops accepting connections, closes the open ones and waits for their handlers to exit
+func (s *Server) Close() error {
+	s.connMu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// handleConn reads commands from a single client until it disconnects or quits
+func (s *Server) handleConn(conn net.Conn) {
+	s.currConnections.Add(1)
+	s.totalConnections.Add(1)
+	defer func() {
+		s.currConnections.Add(-1)
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				w.WriteString("CLIENT_ERROR line too long\r\n")
+				w.Flush()
+			}
+			return
+		}
+		if err := s.dispatch(line, r, w); err != nil {
+			w.Flush()
+			return
+		}
+		// Flush once the pipelined commands already read have been answered
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+var errLineTooLong = errors.New("line too long")
+
+// readLine reads a single \r\n (or \n) terminated command line
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxLineLength {
+			return "", errLineTooLong
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// dispatch runs a single command. A returned error closes the connection.
+func (s *Server) dispatch(line string, r *bufio.Reader, w *bufio.Writer) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		_, err := w.WriteString("ERROR\r\n")
+		return err
+	}
+
+	switch cmd, args := fields[0], fields[1:]; cmd {
+	case "get", "gets":
+		return s.handleGet(args, cmd == "gets", w)
+	case "set", "add", "replace", "cas":
+		return s.handleStore(cmd, args, r, w)
+	case "delete":
+		return s.handleDelete(args, w)
+	case "incr", "decr":
+		return s.handleIncrDecr(cmd == "incr", args, w)
+	case "touch":
+		return s.handleTouch(args, w)
+	case "stats":
+		return s.handleStats(w)
+	case "version":
+		_, err := fmt.Fprintf(w, "VERSION %s\r\n", version)
+		return err
+	case "quit":
+		return errClientQuit
+	default:
+		_, err := w.WriteString("ERROR\r\n")
+		return err
+	}
+}
+
+// handleGet serves get/gets: VALUE <key> <flags> <bytes> [<cas>]\r\n<data>\r\n ... END
+func (s *Server) handleGet(keys []string, withCAS bool, w *bufio.Writer) error {
+	if len(keys) == 0 {
+		_, err := w.WriteString("ERROR\r\n")
+		return err
+	}
+	for _, key := range keys {
+		if len(key) > maxKeyLength {
+			return clientError(w, "bad command line format")
+		}
+	}
+	for _, key := range keys {
+		s.cmdGet.Add(1)
+		item, found := s.cache.GetItem(key)
+		if !found {
+			continue
+		}
+		data := customcache.ToBytes(item.Value)
+		if withCAS {
+			fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", key, item.Flags, len(data), item.CAS)
+		} else {
+			fmt.Fprintf(w, "VALUE %s %d %d\r\n", key, item.Flags, len(data))
+		}
+		w.Write(data)
+		w.WriteString("\r\n")
+	}
+	_, err := w.WriteString("END\r\n")
+	return err
+}
+
+// handleStore serves <cmd> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]\r\n<data>\r\n
+func (s *Server) handleStore(cmd string, args []string, r *bufio.Reader, w *bufio.Writer) error {
+	want := 4
+	if cmd == "cas" {
+		want = 5
+	}
+	noreply := len(args) == want+1 && args[want] == "noreply"
+	if len(args) != want && !noreply {
+		return s.rejectStore(args, r, w)
+	}
+
+	key := args[0]
+	flags, errFlags := strconv.ParseUint(args[1], 10, 32)
+	exptime, errExp := strconv.ParseInt(args[2], 10, 64)
+	size, errSize := strconv.Atoi(args[3])
+	var casUnique uint64
+	var errCAS error
+	if cmd == "cas" {
+		casUnique, errCAS = strconv.ParseUint(args[4], 10, 64)
+	}
+	if errFlags != nil || errExp != nil || errSize != nil || errCAS != nil || size < 0 || len(key) > maxKeyLength {
+		return s.rejectStore(args, r, w)
+	}
+
+	if size > maxValueSize {
+		// Swallow the data block so the connection stays in sync
+		if _, err := io.CopyN(io.Discard, r, int64(size)+2); err != nil {
+			return err
+		}
+		_, err := w.WriteString("SERVER_ERROR object too large for cache\r\n")
+		return err
+	}
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		return clientError(w, "bad data chunk")
+	}
+	data = data[:size]
+
+	s.cmdSet.Add(1)
+	item := customcache.Item{Value: data, Flags: uint32(flags), ExpiresAt: exptimeToDeadline(exptime)}
+	reply := s.store(cmd, key, item, casUnique)
+	return s.reply(w, noreply, reply)
+}
+
+// rejectStore answers a malformed storage command line. If the line still carries a usable
+// <bytes> field the client is about to send that data block, so it is swallowed first;
+// otherwise it would be parsed as the next command.
+func (s *Server) rejectStore(args []string, r *bufio.Reader, w *bufio.Writer) error {
+	if len(args) >= 4 {
+		if size, err := strconv.Atoi(args[3]); err == nil && size >= 0 && size <= maxValueSize {
+			if _, err := io.CopyN(io.Discard, r, int64(size)+2); err != nil {
+				return err
+			}
+		}
+	}
+	return clientError(w, "bad command line format")
+}
+
+// store applies a storage command and returns the protocol reply
+func (s *Server) store(cmd, key string, item customcache.Item, casUnique uint64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.cache.Peek(key) // Not a client read: no stats, no read-through
+	switch {
+	case cmd == "add" && exists:
+		return "NOT_STORED"
+	case cmd == "replace" && !exists:
+		return "NOT_STORED"
+	case cmd == "cas" && !exists:
+		return "NOT_FOUND"
+	case cmd == "cas" && current.CAS != casUnique:
+		return "EXISTS"
+	}
+	if s.cache.SetItem(key, item) == 0 {
+		return "SERVER_ERROR store failed"
+	}
+	return "STORED"
+}
+
+// handleDelete serves delete <key> [noreply]
+func (s *Server) handleDelete(args []string, w *bufio.Writer) error {
+	noreply := len(args) == 2 && args[1] == "noreply"
+	if len(args) != 1 && !noreply {
+		return clientError(w, "bad command line format")
+	}
+
+	s.mu.Lock()
+	_, exists := s.cache.Peek(args[0])
+	if exists {
+		s.cache.Delete(args[0])
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		return s.reply(w, noreply, "NOT_FOUND")
+	}
+	return s.reply(w, noreply, "DELETED")
+}
+
+// handleIncrDecr serves incr/decr <key> <value> [noreply]. Incr wraps at 64 bits, decr stops at 0.
+func (s *Server) handleIncrDecr(incr bool, args []string, w *bufio.Writer) error {
+	noreply := len(args) == 3 && args[2] == "noreply"
+	if len(args) != 2 && !noreply {
+		return clientError(w, "bad command line format")
+	}
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return clientError(w, "invalid numeric delta argument")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, found := s.cache.Peek(args[0])
+	if !found {
+		return s.reply(w, noreply, "NOT_FOUND")
+	}
+	current, err := strconv.ParseUint(string(customcache.ToBytes(item.Value)), 10, 64)
+	if err != nil {
+		return clientError(w, "cannot increment or decrement non-numeric value")
+	}
+
+	switch {
+	case incr:
+		current += delta
+	case delta > current:
+		current = 0
+	default:
+		current -= delta
+	}
+	item.Value = []byte(strconv.FormatUint(current, 10))
+	if s.cache.SetItem(args[0], item) == 0 { // Keeps the item's flags and expiry
+		return s.reply(w, noreply, "SERVER_ERROR store failed")
+	}
+	return s.reply(w, noreply, strconv.FormatUint(current, 10))
+}
+
+// handleTouch serves touch <key> <exptime> [noreply]
+func (s *Server) handleTouch(args []string, w *bufio.Writer) error {
+	noreply := len(args) == 3 && args[2] == "noreply"
+	if len(args) != 2 && !noreply {
+		return clientError(w, "bad command line format")
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return clientError(w, "invalid exptime argument")
+	}
+
+	s.cmdTouch.Add(1)
+	var ttl time.Duration
+	if deadline := exptimeToDeadline(exptime); !deadline.IsZero() {
+		ttl = time.Until(deadline)
+		if ttl <= 0 {
+			ttl = time.Nanosecond // Already in the past: expire right away instead of never
+		}
+	}
+
+	s.mu.Lock()
+	touched := s.cache.Touch(args[0], ttl)
+	s.mu.Unlock()
+
+	if !touched {
+		return s.reply(w, noreply, "NOT_FOUND")
+	}
+	return s.reply(w, noreply, "TOUCHED")
+}
+
+// handleStats serves stats as a list of STAT <name> <value> lines
+func (s *Server) handleStats(w *bufio.Writer) error {
+	stats := s.cache.Stats()
+	now := time.Now()
+	lines := []struct {
+		name  string
+		value interface{}
+	}{
+		{"pid", os.Getpid()},
+		{"uptime", int64(now.Sub(s.started).Seconds())},
+		{"time", now.Unix()},
+		{"version", version},
+		{"curr_connections", s.currConnections.Load()},
+		{"total_connections", s.totalConnections.Load()},
+		{"cmd_get", s.cmdGet.Load()},
+		{"cmd_set", s.cmdSet.Load()},
+		{"cmd_touch", s.cmdTouch.Load()},
+		{"get_hits", stats.Hits},
+		{"get_misses", stats.Misses},
+		{"curr_items", stats.Size},
+		{"total_items", stats.Sets},
+		{"evictions", stats.Evictions},
+		{"expired", stats.Expirations},
+	}
+	for _, line := range lines {
+		fmt.Fprintf(w, "STAT %s %v\r\n", line.name, line.value)
+	}
+	_, err := w.WriteString("END\r\n")
+	return err
+}
+
+// reply writes a single-line response unless the client asked for noreply
+func (s *Server) reply(w *bufio.Writer, noreply bool, line string) error {
+	if noreply {
+		return nil
+	}
+	_, err := w.WriteString(line + "\r\n")
+	return err
+}
+
+// clientError reports a malformed request
+func clientError(w *bufio.Writer, msg string) error {
+	_, err := fmt.Fprintf(w, "CLIENT_ERROR %s\r\n", msg)
+	return err
+}
+
+// exptimeToDeadline converts a memcached exptime into an Item deadline.
+// 0 never expires, up to 30 days is relative seconds, anything larger is a unix timestamp,
+// and a negative value is already expired.
+func exptimeToDeadline(exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return time.Now()
+	case exptime <= maxRelativeExptime:
+		return time.Now().Add(time.Duration(exptime) * time.Second)
+	default:
+		return time.Unix(exptime, 0)
+	}
+}
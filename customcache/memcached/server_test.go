@@ -0,0 +1,83 @@
+package memcached
+
+import (
+	"bufio"
+	"go-ex/customcache"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer serves cache on a loopback port and returns a raw connection to it
+func startServer(t *testing.T, cache *customcache.ConcurrentCache) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	server := NewServer(cache)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		cache.Close()
+	})
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+// roundTrip sends request and reads back the given number of reply lines
+func roundTrip(t *testing.T, conn net.Conn, r *bufio.Reader, request string, lines int) []string {
+	t.Helper()
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]string, lines)
+	for i := range reply {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading reply to %q: %v", request, err)
+		}
+		reply[i] = strings.TrimRight(line, "\r\n")
+	}
+	return reply
+}
+
+func TestWritesDoNotCountReads(t *testing.T) {
+	cache := customcache.NewConcurrentCache()
+	conn, r := startServer(t, cache)
+
+	roundTrip(t, conn, r, "add k 0 0 1\r\n1\r\n", 1)
+	roundTrip(t, conn, r, "replace k 0 0 1\r\n2\r\n", 1)
+	roundTrip(t, conn, r, "incr k 5\r\n", 1)
+	roundTrip(t, conn, r, "delete k\r\n", 1)
+	roundTrip(t, conn, r, "delete k\r\n", 1)
+
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatalf("write commands counted %d hits and %d misses, want none", stats.Hits, stats.Misses)
+	}
+}
+
+func TestMalformedStorageLineSwallowsData(t *testing.T) {
+	conn, r := startServer(t, customcache.NewConcurrentCache())
+
+	// An unparseable exptime still announces a 3 byte data block
+	reply := roundTrip(t, conn, r, "set k 0 soon 3\r\nget\r\nversion\r\n", 2)
+	if !strings.HasPrefix(reply[0], "CLIENT_ERROR") || !strings.HasPrefix(reply[1], "VERSION") {
+		t.Fatalf("reply = %q, want CLIENT_ERROR then VERSION", reply)
+	}
+}
+
+func TestGetRejectsLongKeys(t *testing.T) {
+	conn, r := startServer(t, customcache.NewConcurrentCache())
+
+	reply := roundTrip(t, conn, r, "get "+strings.Repeat("k", maxKeyLength+1)+"\r\n", 1)
+	if !strings.HasPrefix(reply[0], "CLIENT_ERROR") {
+		t.Fatalf("reply = %q, want CLIENT_ERROR", reply)
+	}
+}
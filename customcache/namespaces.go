@@ -0,0 +1,225 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ex/pkg/cachestats"
+	"sort"
+	"sync"
+)
+
+// When several teams share one cache process, one noisy team filling the cache evicts everyone
+// else. A NamespacedCache gives every namespace its own ConcurrentCache: its own entry and byte
+// quota, its own LRU eviction and its own stats, while still living in one process behind one
+// server (see httpapi.NewNamespacedHandler).
+
+// DefaultNamespace is the namespace used by front-ends that don't name one
+const DefaultNamespace = "default"
+
+// ErrNamespaceLimit is returned when creating a namespace would pass MaxNamespaces
+var ErrNamespaceLimit = errors.New("customcache: namespace limit reached")
+
+// ErrClosed is returned when a namespace is created after the NamespacedCache was closed
+var ErrClosed = errors.New("customcache: cache is closed")
+
+// NamespaceQuota limits a single namespace (0 means no limit)
+type NamespaceQuota struct {
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// NamespacedCacheConfig holds the NamespacedCache settings
+type NamespacedCacheConfig struct {
+	// Settings every namespace is created with. MaxEntries and MaxBytes come from the quota instead,
+	// SnapshotPath and AOFPath are ignored since namespaces would overwrite each other's files, and
+	// keys written to Store are prefixed with "<namespace>/".
+	Base ConcurrentCacheConfig
+
+	DefaultQuota NamespaceQuota            // Quota of namespaces not listed in Quotas
+	Quotas       map[string]NamespaceQuota // Per-namespace quotas
+
+	// How many namespaces besides DefaultNamespace and those in Quotas may be created on first use
+	// (0 means none: only the configured namespaces exist)
+	MaxNamespaces int
+}
+
+// NamespacedCache is a set of independently limited caches. DefaultNamespace and the namespaces
+// in Quotas always exist; up to MaxNamespaces others are created on first use.
+type NamespacedCache struct {
+	mu         sync.RWMutex
+	namespaces map[string]*ConcurrentCache
+	created    int // Namespaces created on first use, counted against MaxNamespaces
+	config     NamespacedCacheConfig
+	closed     bool
+}
+
+// NewNamespacedCache creates a NamespacedCache holding the configured namespaces
+func NewNamespacedCache(config NamespacedCacheConfig) *NamespacedCache {
+	c := &NamespacedCache{namespaces: make(map[string]*ConcurrentCache), config: config}
+	c.namespaces[DefaultNamespace] = c.newNamespace(DefaultNamespace)
+	for namespace := range config.Quotas {
+		if _, ok := c.namespaces[namespace]; !ok {
+			c.namespaces[namespace] = c.newNamespace(namespace)
+		}
+	}
+	return c
+}
+
+// prefixedStore keeps the keys of different namespaces apart in a shared BackingStore
+type prefixedStore struct {
+	BackingStore
+	prefix string
+}
+
+func (s prefixedStore) Load(ctx context.Context, key string) (interface{}, error) {
+	return s.BackingStore.Load(ctx, s.prefix+key)
+}
+
+func (s prefixedStore) Store(ctx context.Context, key string, value interface{}) error {
+	return s.BackingStore.Store(ctx, s.prefix+key, value)
+}
+
+func (s prefixedStore) Delete(ctx context.Context, key string) error {
+	return s.BackingStore.Delete(ctx, s.prefix+key)
+}
+
+// Namespace returns the cache of namespace, creating it with its quota on first use. It fails
+// with ErrNamespaceLimit if that would pass MaxNamespaces, and with ErrClosed after Close.
+func (c *NamespacedCache) Namespace(namespace string) (*ConcurrentCache, error) {
+	if cache, ok := c.Lookup(namespace); ok {
+		return cache, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cache, ok := c.namespaces[namespace]; ok {
+		return cache, nil // Created by someone else in the meantime
+	}
+	if c.closed {
+		return nil, ErrClosed
+	}
+	if c.created >= c.config.MaxNamespaces {
+		return nil, fmt.Errorf("%w: %d namespaces besides the configured ones", ErrNamespaceLimit, c.config.MaxNamespaces)
+	}
+	cache := c.newNamespace(namespace)
+	c.namespaces[namespace] = cache
+	c.created++
+	return cache, nil
+}
+
+// newNamespace creates the cache of namespace with its quota
+func (c *NamespacedCache) newNamespace(namespace string) *ConcurrentCache {
+	quota, ok := c.config.Quotas[namespace]
+	if !ok {
+		quota = c.config.DefaultQuota
+	}
+	config := c.config.Base
+	config.MaxEntries, config.MaxBytes = quota.MaxEntries, quota.MaxBytes
+	config.SnapshotPath, config.AOFPath = "", ""
+	if config.Store != nil {
+		config.Store = prefixedStore{BackingStore: config.Store, prefix: namespace + "/"}
+	}
+	return NewConcurrentCacheWithConfig(config)
+}
+
+// Lookup returns the cache of namespace without creating it
+func (c *NamespacedCache) Lookup(namespace string) (*ConcurrentCache, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cache, ok := c.namespaces[namespace]
+	return cache, ok
+}
+
+// Purge drops every entry of namespace from memory and returns how many were dropped.
+// The namespace's keys in Store are kept; see ConcurrentCache.Purge.
+func (c *NamespacedCache) Purge(namespace string) int {
+	cache, ok := c.Lookup(namespace)
+	if !ok {
+		return 0
+	}
+	return cache.Purge()
+}
+
+// Namespaces returns the names of all namespaces in use, sorted
+func (c *NamespacedCache) Namespaces() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the stats of a single namespace
+func (c *NamespacedCache) Stats(namespace string) (cachestats.Stats, bool) {
+	cache, ok := c.Lookup(namespace)
+	if !ok {
+		return cachestats.Stats{}, false
+	}
+	return cache.Stats(), true
+}
+
+// AllStats returns the stats of every namespace
+func (c *NamespacedCache) AllStats() map[string]cachestats.Stats {
+	stats := make(map[string]cachestats.Stats)
+	for _, name := range c.Namespaces() {
+		if s, ok := c.Stats(name); ok {
+			stats[name] = s
+		}
+	}
+	return stats
+}
+
+// Close closes every namespace
+func (c *NamespacedCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var errs []error
+	for _, cache := range c.namespaces {
+		errs = append(errs, cache.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func RunNamespaces() {
+	cache := NewNamespacedCache(NamespacedCacheConfig{
+		Base:         ConcurrentCacheConfig{Quiet: true}, // Thousands of writes follow
+		DefaultQuota: NamespaceQuota{MaxEntries: 1000},
+		Quotas: map[string]NamespaceQuota{
+			"analytics": {MaxEntries: 100, MaxBytes: 64 << 10}, // The noisy team gets a tight quota
+		},
+		MaxNamespaces: 2, // Room for search and billing
+	})
+	defer cache.Close()
+
+	search, _ := cache.Namespace("search")
+	for i := 0; i < 50; i++ {
+		search.Set(fmt.Sprintf("query:%d", i), fmt.Sprintf("results for query %d", i))
+	}
+
+	// The analytics team writes far more than its quota; only its own entries get evicted
+	analytics, _ := cache.Lookup("analytics") // Configured, so it already exists
+	for i := 0; i < 10_000; i++ {
+		analytics.Set(fmt.Sprintf("event:%d", i), make([]byte, 256))
+	}
+	search.Get("query:0")
+
+	// The same key in two namespaces are two different entries
+	billing, _ := cache.Namespace("billing")
+	billing.Set("query:0", "invoice 1")
+	value, _ := search.Get("query:0")
+	fmt.Printf("search query:0 = %v\n", value)
+	if _, err := cache.Namespace("marketing"); err != nil {
+		fmt.Printf("Namespace(marketing): %v\n", err)
+	}
+
+	for _, name := range cache.Namespaces() {
+		stats, _ := cache.Stats(name)
+		fmt.Printf("%-9s %s\n", name, stats)
+	}
+	fmt.Printf("Purge(analytics) dropped %d entries\n", cache.Purge("analytics"))
+}
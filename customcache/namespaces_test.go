@@ -0,0 +1,34 @@
+package customcache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPurgeKeepsStoreData(t *testing.T) {
+	store := newTestFileStore(t)
+	cache := NewNamespacedCache(NamespacedCacheConfig{
+		Base:   ConcurrentCacheConfig{Store: store, WriteMode: WriteThrough},
+		Quotas: map[string]NamespaceQuota{"team": {}},
+	})
+	defer cache.Close()
+	team, _ := cache.Lookup("team")
+	team.Set("a", "1")
+	team.Set("b", "2")
+
+	if purged := cache.Purge("team"); purged != 2 {
+		t.Fatalf("Purge = %d, want 2", purged)
+	}
+	if size := team.Stats().Size; size != 0 {
+		t.Fatalf("%d entries left in memory after Purge", size)
+	}
+	if value, err := store.Load(context.Background(), "team/a"); err != nil || value != "1" {
+		t.Fatalf("store lost team/a: %v, %v", value, err)
+	}
+	if value, ok := team.Get("b"); !ok || value != "2" {
+		t.Fatalf("Get(b) after Purge = %v, %t, want it read through from the store", value, ok)
+	}
+	if purged := cache.Purge("unknown"); purged != 0 {
+		t.Fatalf("Purge of an unknown namespace = %d", purged)
+	}
+}
@@ -0,0 +1,354 @@
+package peercache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"go-ex/pkg/cachestats"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// When several instances each keep their own ConcurrentCache, every one of them hits the DB for
+// the same keys. PeerCache (in the style of groupcache) gives every key a single owner picked by a
+// consistent hash ring. The owner loads and caches the key; other nodes fetch it from the owner over
+// HTTP and keep a short-lived "hot" copy so a popular key doesn't hammer its owner either.
+//
+// Both caches have a byte budget. Like groupcache, a node only keeps a hot copy of a sample of the
+// values it fetches (1 in HotSampleRate): a popular key is fetched often and soon gets sampled,
+// while a key read once rarely takes up room that the popular ones need.
+
+// Loader loads the value for a key from the data source. It runs only on the key's owner
+// (or locally if the owner can't be reached). Return customcache.ErrNotFound for missing keys.
+type Loader func(ctx context.Context, key string) ([]byte, error)
+
+// PeerCacheConfig holds the settings of one node
+type PeerCacheConfig struct {
+	Self       string        // This node's base URL, e.g. "http://127.0.0.1:8001"; must match its entry in SetPeers
+	BasePath   string        // URL path the peers serve keys under (default "/_peercache/")
+	Replicas   int           // Virtual nodes per peer on the hash ring (default 50)
+	HotTTL     time.Duration // How long a non-owner keeps a value fetched from its owner (default 10s)
+	OwnedTTL   time.Duration // TTL of values this node owns (0 never expires)
+	Loader     Loader
+	HTTPClient *http.Client // Used to fetch from peers (default: 2s timeout)
+
+	OwnedMaxBytes int64 // Byte budget of the values this node owns (default 64 MB)
+	HotMaxBytes   int64 // Byte budget of the hot copies (default OwnedMaxBytes/8)
+	HotMaxEntries int   // Most hot copies kept at once (0 means only HotMaxBytes applies)
+	HotSampleRate int   // Keep a hot copy of 1 in this many fetched values (default 10, 1 keeps all)
+
+	// How often expired hot copies, owned values and remembered misses are swept (default HotTTL)
+	CleanupInterval time.Duration
+}
+
+// PeerStats reports where values were served from
+type PeerStats struct {
+	Owned       cachestats.Stats // Keys this node owns
+	Hot         cachestats.Stats // Copies of keys owned by other nodes
+	Loads       uint64           // Loader calls made by this node
+	PeerFetches uint64           // Successful fetches from other nodes
+	PeerErrors  uint64           // Failed fetches that fell back to a local load
+}
+
+// PeerCache is one node of a distributed cache
+type PeerCache struct {
+	config PeerCacheConfig
+	owned  *customcache.ConcurrentCache
+	hot    *customcache.ConcurrentCache
+
+	mu      sync.RWMutex
+	ring    *HashRing
+	fetches fetchGroup
+
+	loads       atomic.Uint64
+	peerFetches atomic.Uint64
+	peerErrors  atomic.Uint64
+}
+
+// NewPeerCache creates a node. Call SetPeers before use and mount the node as an http.Handler
+// on its Self address so other nodes can fetch from it.
+func NewPeerCache(config PeerCacheConfig) *PeerCache {
+	if config.BasePath == "" {
+		config.BasePath = "/_peercache/"
+	}
+	if config.Replicas <= 0 {
+		config.Replicas = 50
+	}
+	if config.HotTTL <= 0 {
+		config.HotTTL = 10 * time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 2 * time.Second}
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = config.HotTTL
+	}
+	if config.OwnedMaxBytes <= 0 {
+		config.OwnedMaxBytes = 64 << 20
+	}
+	if config.HotMaxBytes <= 0 {
+		config.HotMaxBytes = max(config.OwnedMaxBytes/8, 1)
+	}
+	if config.HotSampleRate <= 0 {
+		config.HotSampleRate = 10
+	}
+	config.Self = strings.TrimRight(config.Self, "/")
+	return &PeerCache{
+		config: config,
+		owned: customcache.NewConcurrentCacheWithConfig(customcache.ConcurrentCacheConfig{
+			DefaultTTL:      config.OwnedTTL,
+			CleanupInterval: config.CleanupInterval,
+			MaxBytes:        config.OwnedMaxBytes,
+			Quiet:           true,
+		}),
+		hot: customcache.NewConcurrentCacheWithConfig(customcache.ConcurrentCacheConfig{
+			DefaultTTL:      config.HotTTL,
+			CleanupInterval: config.CleanupInterval,
+			MaxBytes:        config.HotMaxBytes,
+			MaxEntries:      config.HotMaxEntries,
+			Quiet:           true,
+		}),
+		ring: NewHashRing(config.Replicas),
+	}
+}
+
+// Close stops the background sweeps of the node's caches. The node must not be used afterwards.
+func (p *PeerCache) Close() error {
+	return errors.Join(p.owned.Close(), p.hot.Close())
+}
+
+// SetPeers replaces the set of nodes (including this one) that keys are spread over
+func (p *PeerCache) SetPeers(peers ...string) {
+	ring := NewHashRing(p.config.Replicas)
+	for _, peer := range peers {
+		ring.Add(strings.TrimRight(peer, "/"))
+	}
+	p.mu.Lock()
+	p.ring = ring
+	p.mu.Unlock()
+}
+
+// owner returns the node that owns key
+func (p *PeerCache) owner(key string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ring.Get(key)
+}
+
+// Get returns the value for key, from this node's caches, from the key's owner, or from the loader
+func (p *PeerCache) Get(ctx context.Context, key string) ([]byte, error) {
+	owner := p.owner(key)
+	if owner == "" || owner == p.config.Self {
+		return p.getOwned(ctx, key)
+	}
+
+	if value, found := p.hot.Get(key); found {
+		return value.([]byte), nil
+	}
+
+	// Concurrent misses on this node share one fetch, whose result may be kept hot for HotTTL
+	loadCtx := context.WithoutCancel(ctx) // One caller giving up doesn't fail the fetch for the others
+	return p.fetches.do(ctx, key, func() ([]byte, error) {
+		if item, found := p.hot.Peek(key); found { // Kept by a fetch that finished while we waited
+			return item.Value.([]byte), nil
+		}
+		value, err := p.fetch(loadCtx, owner, key)
+		if errors.Is(err, customcache.ErrNotFound) {
+			return nil, err
+		}
+		if err != nil {
+			p.peerErrors.Add(1)
+			if value, err = p.load(loadCtx, key); err != nil { // The owner is unreachable, so load locally rather than fail
+				return nil, err
+			}
+		}
+		if rand.Intn(p.config.HotSampleRate) == 0 {
+			p.hot.Set(key, value)
+		}
+		return value, nil
+	})
+}
+
+// fetchGroup shares one fetch between the concurrent misses for a key on this node
+type fetchGroup struct {
+	mu    sync.Mutex
+	calls map[string]*fetchCall
+}
+
+// fetchCall is a fetch in flight and, once done is closed, its result
+type fetchCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+var errFetchPanicked = errors.New("peercache: fetch panicked")
+
+// do runs fn for key unless a fetch for key is already in flight, in which case it waits for
+// that fetch's result or for ctx to be done
+func (g *fetchGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*fetchCall)
+	}
+	if cl, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-cl.done:
+			return cl.value, cl.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	cl := &fetchCall{done: make(chan struct{}), err: errFetchPanicked}
+	g.calls[key] = cl
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(cl.done)
+	}()
+	cl.value, cl.err = fn()
+	return cl.value, cl.err
+}
+
+// getOwned serves a key this node owns, loading it on a miss
+func (p *PeerCache) getOwned(ctx context.Context, key string) ([]byte, error) {
+	value, err := p.owned.GetOrLoad(ctx, key, func(ctx context.Context, key string) (interface{}, error) {
+		return p.load(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+func (p *PeerCache) load(ctx context.Context, key string) ([]byte, error) {
+	p.loads.Add(1)
+	return p.config.Loader(ctx, key)
+}
+
+// fetch asks the owner node for key
+func (p *PeerCache) fetch(ctx context.Context, owner, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, owner+p.config.BasePath+url.PathEscape(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		p.peerFetches.Add(1)
+		return body, nil
+	case http.StatusNotFound:
+		return nil, customcache.ErrNotFound
+	default:
+		return nil, fmt.Errorf("peercache: %s returned %s: %s", owner, resp.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+// ServeHTTP answers fetches from other nodes. It always serves from this node's owned cache and
+// never forwards, so nodes with briefly different peer lists can't bounce a request around.
+func (p *PeerCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, p.config.BasePath) {
+		http.NotFound(w, r)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, p.config.BasePath)
+
+	value, err := p.getOwned(r.Context(), key)
+	switch {
+	case errors.Is(err, customcache.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(value)
+	}
+}
+
+// Stats returns a snapshot of where this node's values came from
+func (p *PeerCache) Stats() PeerStats {
+	return PeerStats{
+		Owned:       p.owned.Stats(),
+		Hot:         p.hot.Stats(),
+		Loads:       p.loads.Load(),
+		PeerFetches: p.peerFetches.Load(),
+		PeerErrors:  p.peerErrors.Load(),
+	}
+}
+
+func RunPeerCache() {
+	var dbQueries atomic.Int32
+	loadFromDB := func(ctx context.Context, key string) ([]byte, error) {
+		dbQueries.Add(1)
+		return []byte("value_for_" + key), nil
+	}
+
+	// Start three in-process nodes on loopback
+	var nodes []*PeerCache
+	var peers []string
+	var servers []*http.Server
+	for i := 0; i < 3; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			fmt.Printf("Failed to listen: %v\n", err)
+			return
+		}
+		self := "http://" + l.Addr().String()
+		node := NewPeerCache(PeerCacheConfig{Self: self, Loader: loadFromDB, HotTTL: time.Second})
+		server := &http.Server{Handler: node}
+		go server.Serve(l)
+
+		nodes = append(nodes, node)
+		peers = append(peers, self)
+		servers = append(servers, server)
+	}
+	defer func() {
+		for i, server := range servers {
+			server.Close()
+			nodes[i].Close()
+		}
+	}()
+	for _, node := range nodes {
+		node.SetPeers(peers...)
+	}
+
+	// Every node asks for every key; each key should hit the DB only once, on its owner
+	keys := []string{"user:1", "user:2", "user:3", "product:10", "product:20", "order:abc"}
+	for _, node := range nodes {
+		for _, key := range keys {
+			value, err := node.Get(context.Background(), key)
+			if err != nil {
+				fmt.Printf("Get(%s) failed: %v\n", key, err)
+				continue
+			}
+			fmt.Printf("%s Get(%s) = %s (owner %s)\n", node.config.Self, key, value, node.owner(key))
+		}
+	}
+	fmt.Printf("DB queries for %d keys requested from %d nodes: %d\n", len(keys), len(nodes), dbQueries.Load())
+	for _, node := range nodes {
+		stats := node.Stats()
+		fmt.Printf("%s owned=%d hot=%d loads=%d peer_fetches=%d\n",
+			node.config.Self, stats.Owned.Size, stats.Hot.Size, stats.Loads, stats.PeerFetches)
+	}
+}
@@ -0,0 +1,248 @@
+package peercache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startNodes runs n nodes with the same config on loopback HTTP servers
+func startNodes(t *testing.T, n int, config PeerCacheConfig) ([]*PeerCache, []*httptest.Server) {
+	t.Helper()
+	nodes := make([]*PeerCache, n)
+	servers := make([]*httptest.Server, n)
+	var peers []string
+	for i := range nodes {
+		// The listener comes first so the node knows its own URL before it starts serving
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.Self = "http://" + l.Addr().String()
+		nodes[i] = NewPeerCache(config)
+		servers[i] = httptest.NewUnstartedServer(nodes[i])
+		servers[i].Listener.Close()
+		servers[i].Listener = l
+		servers[i].Start()
+		peers = append(peers, config.Self)
+	}
+	for _, node := range nodes {
+		node.SetPeers(peers...)
+	}
+	t.Cleanup(func() {
+		for i := range nodes {
+			servers[i].Close()
+			nodes[i].Close()
+		}
+	})
+	return nodes, servers
+}
+
+// countingLoader returns "value:<key>" and counts calls per key
+func countingLoader(calls *sync.Map) Loader {
+	return func(ctx context.Context, key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, customcache.ErrNotFound
+		}
+		n, _ := calls.LoadOrStore(key, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+		return []byte("value:" + key), nil
+	}
+}
+
+func TestEveryKeyIsLoadedOnceByItsOwner(t *testing.T) {
+	var calls sync.Map
+	nodes, _ := startNodes(t, 3, PeerCacheConfig{Loader: countingLoader(&calls)})
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+	for _, node := range nodes {
+		for _, key := range keys {
+			value, err := node.Get(context.Background(), key)
+			if err != nil || string(value) != "value:"+key {
+				t.Fatalf("Get(%s) = %q, %v", key, value, err)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		n, _ := calls.Load(key)
+		if got := n.(*atomic.Int32).Load(); got != 1 {
+			t.Fatalf("key %s was loaded %d times, want 1", key, got)
+		}
+	}
+	var peerFetches uint64
+	for _, node := range nodes {
+		stats := node.Stats()
+		peerFetches += stats.PeerFetches
+		if stats.PeerErrors != 0 {
+			t.Fatalf("%s had %d peer errors", node.config.Self, stats.PeerErrors)
+		}
+	}
+	if want := uint64(len(keys) * (len(nodes) - 1)); peerFetches != want {
+		t.Fatalf("%d peer fetches, want %d", peerFetches, want)
+	}
+
+	if _, err := nodes[0].Get(context.Background(), "missing"); !errors.Is(err, customcache.ErrNotFound) {
+		t.Fatalf("Get(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUnreachableOwnerFallsBackToLocalLoad(t *testing.T) {
+	var calls sync.Map
+	nodes, servers := startNodes(t, 2, PeerCacheConfig{Loader: countingLoader(&calls)})
+
+	// Find a key owned by the second node, then take that node down
+	var key string
+	for i := 0; key == ""; i++ {
+		if candidate := fmt.Sprintf("key:%d", i); nodes[0].owner(candidate) == servers[1].URL {
+			key = candidate
+		}
+	}
+	servers[1].Close()
+
+	value, err := nodes[0].Get(context.Background(), key)
+	if err != nil || string(value) != "value:"+key {
+		t.Fatalf("Get(%s) = %q, %v", key, value, err)
+	}
+	if stats := nodes[0].Stats(); stats.PeerErrors != 1 || stats.Loads != 1 {
+		t.Fatalf("peer_errors=%d loads=%d, want 1 and 1", stats.PeerErrors, stats.Loads)
+	}
+}
+
+func TestHotCopiesAreSwept(t *testing.T) {
+	var calls sync.Map
+	nodes, servers := startNodes(t, 2, PeerCacheConfig{
+		Loader:          countingLoader(&calls),
+		HotTTL:          20 * time.Millisecond,
+		CleanupInterval: 10 * time.Millisecond,
+		HotSampleRate:   1,
+	})
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		if nodes[0].owner(key) == servers[1].URL {
+			nodes[0].Get(context.Background(), key)
+		}
+	}
+	if nodes[0].Stats().Hot.Size == 0 {
+		t.Fatal("no hot copies were kept")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for nodes[0].Stats().Hot.Size != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d expired hot copies were never swept", nodes[0].Stats().Hot.Size)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// keysOwnedBy returns n keys that nodes[0] fetches from server
+func keysOwnedBy(nodes []*PeerCache, server *httptest.Server, n int) []string {
+	var keys []string
+	for i := 0; len(keys) < n; i++ {
+		if key := fmt.Sprintf("key:%d", i); nodes[0].owner(key) == server.URL {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestCachesStayWithinTheirBudgets(t *testing.T) {
+	var calls sync.Map
+	nodes, servers := startNodes(t, 2, PeerCacheConfig{
+		Loader:        countingLoader(&calls),
+		OwnedMaxBytes: 400,
+		HotMaxBytes:   200,
+		HotMaxEntries: 5,
+		HotSampleRate: 1,
+	})
+
+	for _, key := range keysOwnedBy(nodes, servers[1], 50) {
+		if _, err := nodes[0].Get(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range keysOwnedBy(nodes, servers[0], 50) {
+		if _, err := nodes[0].Get(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := nodes[0].Stats()
+	if stats.Hot.Size > 5 || stats.Hot.Bytes > 200 || stats.Hot.Evictions == 0 {
+		t.Fatalf("hot cache %s, want at most 5 entries and 200 bytes", stats.Hot)
+	}
+	if stats.Owned.Bytes > 400 || stats.Owned.Evictions == 0 {
+		t.Fatalf("owned cache %s, want at most 400 bytes", stats.Owned)
+	}
+}
+
+func TestHotCopiesAreSampled(t *testing.T) {
+	var calls sync.Map
+	nodes, servers := startNodes(t, 2, PeerCacheConfig{Loader: countingLoader(&calls)})
+	keys := keysOwnedBy(nodes, servers[1], 101)
+
+	// Keys read once are kept about one time in ten
+	for _, key := range keys[1:] {
+		nodes[0].Get(context.Background(), key)
+	}
+	if size := nodes[0].Stats().Hot.Size; size > 40 {
+		t.Fatalf("%d of 100 keys read once were kept hot, want about 10", size)
+	}
+
+	// A key read over and over gets sampled and stops costing a fetch every time
+	hot := keys[0]
+	before := nodes[0].Stats().PeerFetches
+	for i := 0; i < 200; i++ {
+		if value, err := nodes[0].Get(context.Background(), hot); err != nil || string(value) != "value:"+hot {
+			t.Fatalf("Get(%s) = %q, %v", hot, value, err)
+		}
+	}
+	if fetches := nodes[0].Stats().PeerFetches - before; fetches >= 100 {
+		t.Fatalf("200 reads of one key made %d peer fetches", fetches)
+	}
+	if _, found := nodes[0].hot.Peek(hot); !found {
+		t.Fatal("the popular key was never kept hot")
+	}
+}
+
+func TestConcurrentMissesShareOneFetch(t *testing.T) {
+	var calls sync.Map
+	release := make(chan struct{})
+	loader := countingLoader(&calls)
+	nodes, servers := startNodes(t, 2, PeerCacheConfig{
+		Loader: func(ctx context.Context, key string) ([]byte, error) {
+			<-release
+			return loader(ctx, key)
+		},
+		HotSampleRate: 1000, // Practically never kept, so only the shared fetch can save requests
+	})
+	key := keysOwnedBy(nodes, servers[1], 1)[0]
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := nodes[0].Get(context.Background(), key); err != nil || string(value) != "value:"+key {
+				t.Errorf("Get(%s) = %q, %v", key, value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // Let every Get join the fetch
+	close(release)
+	wg.Wait()
+	if fetches := nodes[0].Stats().PeerFetches; fetches != 1 {
+		t.Fatalf("10 concurrent misses made %d peer fetches, want 1", fetches)
+	}
+}
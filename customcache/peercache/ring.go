@@ -0,0 +1,56 @@
+package peercache
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// HashRing places keys on nodes with consistent hashing. Every node is added to the ring
+// several times (virtual nodes) so keys spread evenly and only about 1/N of them move
+// when a node joins or leaves.
+type HashRing struct {
+	replicas int
+	hashes   []uint32          // Sorted positions of all virtual nodes
+	nodes    map[uint32]string // Virtual node position -> node
+}
+
+// NewHashRing creates an empty ring with the given number of virtual nodes per node
+func NewHashRing(replicas int) *HashRing {
+	if replicas < 1 {
+		replicas = 1
+	}
+	return &HashRing{
+		replicas: replicas,
+		nodes:    make(map[uint32]string),
+	}
+}
+
+// Add puts nodes on the ring
+func (r *HashRing) Add(nodes ...string) {
+	for _, node := range nodes {
+		for i := 0; i < r.replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + node))
+			if _, taken := r.nodes[hash]; taken {
+				continue // Astronomically rare collision: keep the first owner
+			}
+			r.nodes[hash] = node
+			r.hashes = append(r.hashes, hash)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Get returns the node that owns key, or "" if the ring is empty
+func (r *HashRing) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	// The owner is the first virtual node clockwise from the key's position
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0 // Wrap around the ring
+	}
+	return r.nodes[r.hashes[i]]
+}
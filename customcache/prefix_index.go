@@ -0,0 +1,133 @@
+package customcache
+
+import "strings"
+
+// prefixIndex is a radix tree over the cache's keys. DeletePrefix uses it to find every key with a
+// given prefix in O(len(prefix) + matches) instead of scanning the whole map.
+type prefixIndex struct {
+	root radixNode
+}
+
+// radixNode is a node of the tree; the key of a node is the concatenation of the labels on the way down
+type radixNode struct {
+	label    string // Edge label leading into this node
+	leaf     bool   // A key ends at this node
+	children map[byte]*radixNode
+}
+
+// commonPrefixLen returns the length of the longest common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// insert adds key to the index
+func (t *prefixIndex) insert(key string) {
+	n := &t.root
+	for {
+		if key == "" {
+			n.leaf = true
+			return
+		}
+		if n.children == nil {
+			n.children = make(map[byte]*radixNode)
+		}
+		child, ok := n.children[key[0]]
+		if !ok {
+			n.children[key[0]] = &radixNode{label: key, leaf: true}
+			return
+		}
+
+		common := commonPrefixLen(child.label, key)
+		if common == len(child.label) {
+			n, key = child, key[common:]
+			continue
+		}
+
+		// Split the edge: n -> mid(common part) -> child(rest of its label)
+		mid := &radixNode{label: child.label[:common], children: map[byte]*radixNode{}}
+		child.label = child.label[common:]
+		mid.children[child.label[0]] = child
+		n.children[key[0]] = mid
+		if common == len(key) {
+			mid.leaf = true
+		} else {
+			mid.children[key[common]] = &radixNode{label: key[common:], leaf: true}
+		}
+		return
+	}
+}
+
+// remove deletes key from the index and prunes the nodes it no longer needs
+func (t *prefixIndex) remove(key string) {
+	t.root.remove(key)
+}
+
+// remove deletes key below n and reports whether n itself can be dropped by its parent
+func (n *radixNode) remove(key string) bool {
+	if key == "" {
+		n.leaf = false
+	} else {
+		child, ok := n.children[key[0]]
+		if !ok || !strings.HasPrefix(key, child.label) {
+			return false // Not in the index
+		}
+		if child.remove(key[len(child.label):]) {
+			delete(n.children, key[0])
+		}
+	}
+
+	if n.leaf || n.label == "" { // Keep keys and the root
+		return false
+	}
+	switch len(n.children) {
+	case 0:
+		return true
+	case 1:
+		// Merge the only child into n to keep the tree compressed
+		for _, only := range n.children {
+			n.label += only.label
+			n.leaf = only.leaf
+			n.children = only.children
+		}
+	}
+	return false
+}
+
+// withPrefix returns every key that starts with prefix
+func (t *prefixIndex) withPrefix(prefix string) []string {
+	n, path := &t.root, ""
+	for prefix != "" {
+		child, ok := n.children[prefix[0]]
+		if !ok {
+			return nil
+		}
+		switch {
+		case strings.HasPrefix(prefix, child.label):
+			n, path, prefix = child, path+child.label, prefix[len(child.label):]
+		case strings.HasPrefix(child.label, prefix):
+			// The prefix ends in the middle of this edge: everything below matches
+			n, path, prefix = child, path+child.label, ""
+		default:
+			return nil
+		}
+	}
+
+	var keys []string
+	var collect func(n *radixNode, path string)
+	collect = func(n *radixNode, path string) {
+		if n.leaf {
+			keys = append(keys, path)
+		}
+		for _, child := range n.children {
+			collect(child, path+child.label)
+		}
+	}
+	collect(n, path)
+	return keys
+}
@@ -0,0 +1,127 @@
+package customcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Slow upstreams make every expiry a latency spike for whoever misses first. Values loaded by
+// GetOrLoad can therefore have two deadlines:
+//
+//   - SoftTTL: once passed, the value is still returned right away but reloaded in the background
+//     (stale-while-revalidate). With RefreshAhead, values that are read shortly before SoftTTL are
+//     reloaded early, so keys that stay hot never go stale at all.
+//   - HardTTL: the value is dropped and the next read has to wait for the loader again.
+//
+// At most one background refresh runs per key; if it fails, the stale value is kept until HardTTL.
+
+// validRefreshConfig disables SoftTTL when it is not below the hard deadline: such a value would
+// expire before it ever went stale, so there is nothing to serve while refreshing.
+func validRefreshConfig(config ConcurrentCacheConfig) ConcurrentCacheConfig {
+	hard := config.HardTTL
+	if hard <= 0 {
+		hard = config.DefaultTTL
+	}
+	if config.SoftTTL > 0 && hard > 0 && config.SoftTTL >= hard {
+		fmt.Printf("Cache: SoftTTL %v is not below HardTTL %v, stale-while-revalidate is disabled\n", config.SoftTTL, hard)
+		config.SoftTTL, config.RefreshAhead = 0, 0
+	}
+	return config
+}
+
+// setLoaded stores a value returned by a loader with the soft and hard deadlines
+func (c *ConcurrentCache) setLoaded(key string, value interface{}) {
+	ttl := c.config.HardTTL
+	if ttl <= 0 {
+		ttl = c.config.DefaultTTL
+	}
+	item := Item{Value: value, ExpiresAt: expiresAt(ttl)}
+	if c.config.SoftTTL > 0 {
+		item.refreshAt = time.Now().Add(c.config.SoftTTL)
+	}
+	c.SetItem(key, item)
+}
+
+// maybeRefresh starts a background reload of item if it is stale or about to become stale
+func (c *ConcurrentCache) maybeRefresh(ctx context.Context, key string, item Item, loader Loader) {
+	if item.refreshAt.IsZero() || time.Now().Before(item.refreshAt.Add(-c.config.RefreshAhead)) {
+		return // Still fresh
+	}
+	if _, running := c.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+	c.logf("Cache: GetOrLoad key '%s' - refreshing in the background\n", key)
+	go c.refresh(context.WithoutCancel(ctx), key, loader) // The reader's request may end before the reload does
+}
+
+// refresh reloads key and stores the new value, keeping the old one if the loader fails
+func (c *ConcurrentCache) refresh(ctx context.Context, key string, loader Loader) {
+	defer c.refreshing.Delete(key)
+	// Shares the call with GetOrLoad misses of the same key
+	_, err := c.loads.do(ctx, key, func() (interface{}, error) {
+		value, err := loader(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-c.stop:
+			return value, nil // Closed while loading; don't write to a closed cache
+		default:
+		}
+		c.setLoaded(key, value)
+		return value, nil
+	})
+	if err != nil {
+		c.logf("Cache: refreshing key '%s' failed, keeping the stale value: %v\n", key, err)
+	}
+}
+
+func RunStaleWhileRevalidate() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		SoftTTL:      time.Millisecond * 200,
+		HardTTL:      time.Second * 2,
+		RefreshAhead: time.Millisecond * 50,
+	})
+	defer cache.Close()
+
+	// Simulated slow upstream that returns a new version on every call
+	var calls atomic.Int32
+	loadPrice := func(ctx context.Context, key string) (interface{}, error) {
+		version := calls.Add(1)
+		time.Sleep(time.Millisecond * 100)
+		return fmt.Sprintf("price v%d", version), nil
+	}
+
+	read := func(label string) {
+		start := time.Now()
+		value, _ := cache.GetOrLoad(context.Background(), "price:42", loadPrice)
+		fmt.Printf("%-22s %-9v in %v\n", label, value, time.Since(start).Round(time.Millisecond))
+	}
+
+	read("First read (miss):")
+	read("Fresh read:")
+
+	time.Sleep(time.Millisecond * 250) // Past SoftTTL
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetOrLoad(context.Background(), "price:42", loadPrice) // Stale, only one refresh starts
+		}()
+	}
+	wg.Wait()
+	read("Stale read:")
+
+	time.Sleep(time.Millisecond * 150) // Let the refresh finish
+	read("After refresh:")
+
+	time.Sleep(time.Millisecond * 120) // Within RefreshAhead of the new SoftTTL
+	read("Refresh-ahead read:")
+	time.Sleep(time.Millisecond * 150)
+	read("After refresh-ahead:")
+	fmt.Printf("Upstream calls: %d\n", calls.Load())
+}
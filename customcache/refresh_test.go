@@ -0,0 +1,38 @@
+package customcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSoftTTLAtOrPastHardTTLIsDisabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ConcurrentCacheConfig
+		want   time.Duration
+	}{
+		{"below HardTTL", ConcurrentCacheConfig{SoftTTL: time.Second, HardTTL: 2 * time.Second}, time.Second},
+		{"equal to HardTTL", ConcurrentCacheConfig{SoftTTL: time.Second, HardTTL: time.Second}, 0},
+		{"past HardTTL", ConcurrentCacheConfig{SoftTTL: 2 * time.Second, HardTTL: time.Second}, 0},
+		{"past DefaultTTL", ConcurrentCacheConfig{SoftTTL: 2 * time.Second, DefaultTTL: time.Second}, 0},
+		{"no hard deadline", ConcurrentCacheConfig{SoftTTL: time.Second}, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewConcurrentCacheWithConfig(tt.config)
+			defer cache.Close()
+			if cache.config.SoftTTL != tt.want {
+				t.Fatalf("SoftTTL = %v, want %v", cache.config.SoftTTL, tt.want)
+			}
+
+			cache.GetOrLoad(context.Background(), "key", func(ctx context.Context, key string) (interface{}, error) {
+				return "value", nil
+			})
+			item, _ := cache.Peek("key")
+			if stale := !item.refreshAt.IsZero(); stale != (tt.want > 0) {
+				t.Fatalf("loaded value has a refresh deadline: %t", stale)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Client is a minimal pure-Go RESP2 client. It is enough to talk to the Server (or a real Redis)
+// from tests and tools without pulling in a Redis library. A Client is safe for concurrent use;
+// commands are sent one at a time over a single connection.
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	w    writer
+}
+
+// ErrNil is returned by the typed helpers when the server replies with a nil bulk string
+var ErrNil = errors.New("resp: nil reply")
+
+// Dial connects to a RESP server at addr
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    writer{bufio.NewWriter(conn)},
+	}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Do sends a command and returns the decoded reply (see readValue for the Go types used).
+// Error replies are returned as an Error.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.w.command(args); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	reply, err := readValue(c.r, maxReplyBulkLength)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Ping checks the connection
+func (c *Client) Ping() error {
+	_, err := c.Do("PING")
+	return err
+}
+
+// Get returns the value of key, or ErrNil if it does not exist
+func (c *Client) Get(key string) ([]byte, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNil
+	}
+	return asBytes(reply)
+}
+
+// Set stores value under key with an optional TTL (0 never expires)
+func (c *Client) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.Do(args...)
+	return err
+}
+
+// Del removes keys and returns how many existed
+func (c *Client) Del(keys ...string) (int64, error) {
+	return c.integer(append([]string{"DEL"}, keys...)...)
+}
+
+// Incr increments the integer stored at key and returns the new value
+func (c *Client) Incr(key string) (int64, error) {
+	return c.integer("INCR", key)
+}
+
+// TTL returns the remaining TTL of key in seconds (-1: no expiry, -2: missing)
+func (c *Client) TTL(key string) (int64, error) {
+	return c.integer("TTL", key)
+}
+
+// Scan walks all keys matching pattern using SCAN
+func (c *Client) Scan(pattern string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := c.Do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("resp: unexpected SCAN reply %v", reply)
+		}
+		next, err := asBytes(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		batch, _ := parts[1].([]interface{})
+		for _, key := range batch {
+			b, err := asBytes(key)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, string(b))
+		}
+		cursor = string(next)
+		if cursor == "0" {
+			return keys, nil
+		}
+	}
+}
+
+// integer sends a command that replies with an integer
+func (c *Client) integer(args ...string) (int64, error) {
+	reply, err := c.Do(args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("resp: unexpected %s reply %v", args[0], reply)
+	}
+	return n, nil
+}
+
+// asBytes converts a bulk or simple string reply to bytes
+func asBytes(reply interface{}) ([]byte, error) {
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("resp: unexpected reply %v", reply)
+	}
+}
@@ -0,0 +1,87 @@
+package resp
+
+// matchGlob reports whether key matches a Redis-style glob pattern.
+// Supported: * (any run), ? (any byte), [abc], [^abc], [a-z] and \ to escape the next byte.
+// Unlike path.Match, * also matches '/', which keys like "user/1" rely on.
+//
+// It runs in O(len(pattern) * len(key)): only the most recent * is ever backtracked to, since
+// anything an earlier * could absorb the later one can absorb as well.
+func matchGlob(pattern, key string) bool {
+	p, k := 0, 0
+	star, starKey := -1, 0 // Pattern position just after the last *, and where in key it is matched up to
+	for k < len(key) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				p++
+				star, starKey = p, k
+				continue
+			case '?':
+				p, k = p+1, k+1
+				continue
+			case '[':
+				if rest, ok := matchClass(pattern[p+1:], key[k]); ok {
+					p, k = len(pattern)-len(rest), k+1
+					continue
+				}
+			case '\\':
+				literal := p
+				if p+1 < len(pattern) {
+					literal = p + 1
+				}
+				if pattern[literal] == key[k] {
+					p, k = literal+1, k+1
+					continue
+				}
+			default:
+				if pattern[p] == key[k] {
+					p, k = p+1, k+1
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// Let the last * absorb one more byte and retry the rest of the pattern from there
+		starKey++
+		p, k = star, starKey
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against a [...] class whose body starts at pattern and
+// returns the pattern after the closing bracket
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		lo := pattern[0]
+		if lo == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+			lo = pattern[0]
+		}
+		pattern = pattern[1:]
+		hi := lo
+		if len(pattern) > 1 && pattern[0] == '-' && pattern[1] != ']' {
+			hi = pattern[1]
+			pattern = pattern[2:]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:] // Skip the closing bracket
+	}
+	return pattern, matched != negate
+}
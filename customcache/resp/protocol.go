@@ -0,0 +1,198 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RESP2 framing shared by the server and the client.
+// Every value starts with a type byte: + simple string, - error, : integer, $ bulk string, * array.
+
+const (
+	maxReplyBulkLength = 512 << 20 // Client side: Redis' own proto-max-bulk-len default
+	maxArrayLength     = 1 << 20
+	maxLineLength      = 64 << 10
+	readChunk          = 64 << 10 // Bulk strings and arrays grow by at most this much before data arrives
+)
+
+// Error is an error reply sent by the server (a RESP "-" value)
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+var errProtocol = errors.New("resp: protocol error")
+
+// readLine reads a \r\n terminated line without the terminator
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxLineLength {
+			return "", errProtocol
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// readValue reads a single RESP value. Bulk strings come back as []byte, nil bulk strings and
+// arrays as nil, simple strings as string, integers as int64, arrays as []interface{} and
+// error replies as Error. Bulk strings longer than maxBulk are a protocol error.
+func readValue(r *bufio.Reader, maxBulk int) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		return readBulk(r, line, maxBulk)
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArrayLength {
+			return nil, errProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, 0, min(n, readChunk/16)) // n is the peer's claim; grow as elements arrive
+		for i := 0; i < n; i++ {
+			value, err := readValue(r, maxBulk)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		return nil, errProtocol
+	}
+}
+
+// readBulk reads the payload of a bulk string whose $<len> header is line. The buffer grows as the
+// payload arrives, so a header promising more than is sent doesn't allocate the full length.
+func readBulk(r *bufio.Reader, line string, maxBulk int) (interface{}, error) {
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxBulk {
+		return nil, errProtocol
+	}
+	if n < 0 {
+		return nil, nil
+	}
+	data := make([]byte, 0, min(n+2, readChunk))
+	for len(data) < n+2 {
+		chunk := min(n+2-len(data), readChunk)
+		start := len(data)
+		data = append(data, make([]byte, chunk)...)
+		if _, err := io.ReadFull(r, data[start:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	if data[n] != '\r' || data[n+1] != '\n' {
+		return nil, errProtocol
+	}
+	return data[:n], nil
+}
+
+// readCommand reads a client command: either an array of bulk strings or an inline
+// space-separated line, which is what telnet-style clients send
+func readCommand(r *bufio.Reader, maxBulk int) ([][]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] != '*' {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		var args [][]byte
+		for _, field := range strings.Fields(line) {
+			args = append(args, []byte(field))
+		}
+		return args, nil
+	}
+
+	value, err := readValue(r, maxBulk)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := value.([]interface{})
+	args := make([][]byte, len(items))
+	for i, item := range items {
+		arg, ok := item.([]byte)
+		if !ok {
+			return nil, errProtocol
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// writer encodes RESP replies
+type writer struct {
+	*bufio.Writer
+}
+
+func (w writer) simple(s string) error {
+	_, err := fmt.Fprintf(w, "+%s\r\n", s)
+	return err
+}
+
+func (w writer) err(msg string) error {
+	_, err := fmt.Fprintf(w, "-%s\r\n", msg)
+	return err
+}
+
+func (w writer) integer(n int64) error {
+	_, err := fmt.Fprintf(w, ":%d\r\n", n)
+	return err
+}
+
+func (w writer) bulk(data []byte) error {
+	if data == nil {
+		_, err := w.WriteString("$-1\r\n")
+		return err
+	}
+	fmt.Fprintf(w, "$%d\r\n", len(data))
+	w.Write(data)
+	_, err := w.WriteString("\r\n")
+	return err
+}
+
+func (w writer) arrayHeader(n int) error {
+	_, err := fmt.Fprintf(w, "*%d\r\n", n)
+	return err
+}
+
+// command encodes a client command as an array of bulk strings
+func (w writer) command(args []string) error {
+	w.arrayHeader(len(args))
+	for _, arg := range args {
+		if err := w.bulk([]byte(arg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
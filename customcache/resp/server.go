@@ -0,0 +1,489 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"hash/fnv"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A RESP2 (Redis serialization protocol) front-end for customcache.ConcurrentCache, for tooling that
+// only speaks Redis. It works with redis-cli:
+//
+//	go run ./cacheserver -protocol resp -addr 127.0.0.1:6379
+//	redis-cli -p 6379 SET greeting hello EX 60
+//
+// Supported commands: GET, SET (EX/PX/NX/XX), DEL, EXISTS, EXPIRE, TTL, INCR, KEYS, SCAN, PING, QUIT.
+
+const (
+	defaultScanCount = 10
+
+	// DefaultMaxBulkBytes is the default ServerConfig.MaxBulkBytes
+	DefaultMaxBulkBytes = 1 << 20
+)
+
+// errQuit ends the connection after QUIT has been answered
+var errQuit = errors.New("client quit")
+
+// ServerConfig holds the optional server settings
+type ServerConfig struct {
+	MaxBulkBytes int // Longest bulk string (key or value) a client may send (default DefaultMaxBulkBytes)
+}
+
+// Server serves a subset of the Redis protocol on top of a ConcurrentCache
+type Server struct {
+	cache  *customcache.ConcurrentCache
+	config ServerConfig
+	mu     sync.Mutex // Serializes write commands so SET NX/XX, INCR and EXPIRE are atomic
+
+	scanMu   sync.Mutex
+	scanKeys []hashedKey // Every key sorted by scanHash, taken when the latest scan started
+
+	connMu   sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+// NewServer creates a RESP server for cache
+func NewServer(cache *customcache.ConcurrentCache) *Server {
+	return NewServerWithConfig(cache, ServerConfig{})
+}
+
+// NewServerWithConfig creates a RESP server for cache with the given settings
+func NewServerWithConfig(cache *customcache.ConcurrentCache, config ServerConfig) *Server {
+	if config.MaxBulkBytes <= 0 {
+		config.MaxBulkBytes = DefaultMaxBulkBytes
+	}
+	return &Server{
+		cache:  cache,
+		config: config,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the TCP address addr and serves clients until Close is called
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and handles each one in its own goroutine
+func (s *Server) Serve(l net.Listener) error {
+	s.connMu.Lock()
+	if s.closed {
+		s.connMu.Unlock()
+		l.Close()
+		return net.ErrClosed
+	}
+	s.listener = l
+	s.connMu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.connMu.Lock()
+			closed := s.closed
+			s.connMu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.connMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.connMu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+// Close stops accepting connections, closes the open ones and waits for their handlers to exit
+func (s *Server) Close() error {
+	s.connMu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// handleConn reads commands from a single client until it disconnects or quits
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := writer{bufio.NewWriter(conn)}
+	for {
+		args, err := readCommand(r, s.config.MaxBulkBytes)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				w.err("ERR Protocol error")
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue // Empty inline line
+		}
+		if err := s.dispatch(args, w); err != nil {
+			w.Flush()
+			return
+		}
+		// Flush once the pipelined commands already read have been answered
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dispatch runs a single command. A returned error closes the connection.
+func (s *Server) dispatch(args [][]byte, w writer) error {
+	name := strings.ToUpper(string(args[0]))
+	arity := map[string]int{ // Minimum number of arguments including the command name
+		"GET": 2, "SET": 3, "DEL": 2, "EXISTS": 2, "EXPIRE": 3, "TTL": 2,
+		"INCR": 2, "KEYS": 2, "SCAN": 2, "PING": 1, "QUIT": 1, "COMMAND": 1,
+	}
+	minArgs, known := arity[name]
+	if !known {
+		return w.err(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	if len(args) < minArgs {
+		return w.err(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+	}
+
+	switch name {
+	case "GET":
+		return s.get(args, w)
+	case "SET":
+		return s.set(args, w)
+	case "DEL":
+		return s.del(args, w)
+	case "EXISTS":
+		return s.exists(args, w)
+	case "EXPIRE":
+		return s.expire(args, w)
+	case "TTL":
+		return s.ttl(args, w)
+	case "INCR":
+		return s.incr(args, w)
+	case "KEYS":
+		return s.keys(args, w)
+	case "SCAN":
+		return s.scan(args, w)
+	case "PING":
+		if len(args) > 1 {
+			return w.bulk(args[1])
+		}
+		return w.simple("PONG")
+	case "QUIT":
+		w.simple("OK")
+		return errQuit
+	default: // COMMAND: redis-cli asks for command docs on startup; an empty list is enough
+		return w.arrayHeader(0)
+	}
+}
+
+// get serves GET key
+func (s *Server) get(args [][]byte, w writer) error {
+	item, found := s.cache.GetItem(string(args[1]))
+	if !found {
+		return w.bulk(nil)
+	}
+	return w.bulk(customcache.ToBytes(item.Value))
+}
+
+// set serves SET key value [EX seconds | PX milliseconds] [NX | XX]
+func (s *Server) set(args [][]byte, w writer) error {
+	key, value := string(args[1]), args[2]
+	var ttl time.Duration
+	var nx, xx bool
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(string(args[i])); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if ttl != 0 || i+1 >= len(args) {
+				return w.err("ERR syntax error")
+			}
+			i++
+			n, err := strconv.ParseInt(string(args[i]), 10, 64)
+			if err != nil || n <= 0 {
+				return w.err("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			if n > math.MaxInt64/int64(unit) {
+				return w.err("ERR invalid expire time in 'set' command") // The TTL would overflow
+			}
+			ttl = time.Duration(n) * unit
+		default:
+			return w.err("ERR syntax error")
+		}
+	}
+	if nx && xx {
+		return w.err("ERR syntax error")
+	}
+
+	s.mu.Lock()
+	_, exists := s.cache.TTL(key)
+	if (nx && exists) || (xx && !exists) {
+		s.mu.Unlock()
+		return w.bulk(nil)
+	}
+	cas := s.cache.SetItem(key, customcache.Item{Value: value, ExpiresAt: deadline(ttl)})
+	s.mu.Unlock()
+
+	if cas == 0 {
+		return w.err("ERR store failed")
+	}
+	return w.simple("OK")
+}
+
+// del serves DEL key [key ...] and returns how many keys were removed
+func (s *Server) del(args [][]byte, w writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed int64
+	for _, arg := range args[1:] {
+		key := string(arg)
+		if _, exists := s.cache.TTL(key); exists {
+			s.cache.Delete(key)
+			removed++
+		}
+	}
+	return w.integer(removed)
+}
+
+// exists serves EXISTS key [key ...] and returns how many of the keys exist
+func (s *Server) exists(args [][]byte, w writer) error {
+	var count int64
+	for _, arg := range args[1:] {
+		if _, exists := s.cache.TTL(string(arg)); exists {
+			count++
+		}
+	}
+	return w.integer(count)
+}
+
+// expire serves EXPIRE key seconds. A non-positive TTL deletes the key, like Redis.
+func (s *Server) expire(args [][]byte, w writer) error {
+	seconds, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return w.err("ERR value is not an integer or out of range")
+	}
+	if seconds > math.MaxInt64/int64(time.Second) {
+		return w.err("ERR invalid expire time in 'expire' command") // The TTL would overflow
+	}
+	key := string(args[1])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seconds <= 0 {
+		_, exists := s.cache.TTL(key)
+		if exists {
+			s.cache.Delete(key)
+			return w.integer(1)
+		}
+		return w.integer(0)
+	}
+	if s.cache.Touch(key, time.Duration(seconds)*time.Second) {
+		return w.integer(1)
+	}
+	return w.integer(0)
+}
+
+// ttl serves TTL key: -2 if the key is missing, -1 if it never expires, else seconds left
+func (s *Server) ttl(args [][]byte, w writer) error {
+	ttl, exists := s.cache.TTL(string(args[1]))
+	switch {
+	case !exists:
+		return w.integer(-2)
+	case ttl == 0:
+		return w.integer(-1)
+	default:
+		return w.integer(int64((ttl + time.Second/2) / time.Second))
+	}
+}
+
+// incr serves INCR key. A missing key counts as 0 and the key's TTL is kept.
+func (s *Server) incr(args [][]byte, w writer) error {
+	key := string(args[1])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, found := s.cache.GetItem(key)
+	var current int64
+	if found {
+		var err error
+		current, err = strconv.ParseInt(string(customcache.ToBytes(item.Value)), 10, 64)
+		if err != nil {
+			return w.err("ERR value is not an integer or out of range")
+		}
+	}
+	if current == math.MaxInt64 {
+		return w.err("ERR increment or decrement would overflow")
+	}
+	current++
+	item.Value = []byte(strconv.FormatInt(current, 10))
+	if s.cache.SetItem(key, item) == 0 {
+		return w.err("ERR store failed")
+	}
+	return w.integer(current)
+}
+
+// keys serves KEYS pattern
+func (s *Server) keys(args [][]byte, w writer) error {
+	pattern := string(args[1])
+	var matched []string
+	for _, key := range s.cache.Keys() {
+		if matchGlob(pattern, key) {
+			matched = append(matched, key)
+		}
+	}
+	sort.Strings(matched)
+	w.arrayHeader(len(matched))
+	for _, key := range matched {
+		w.bulk([]byte(key))
+	}
+	return nil
+}
+
+// hashedKey is a key with its scanHash
+type hashedKey struct {
+	hash uint64
+	key  string
+}
+
+// scan serves SCAN cursor [MATCH pattern] [COUNT count].
+// Keys are walked in order of a stable hash and the cursor is the next hash to visit, so a key
+// that exists for the whole scan is returned at least once even while other keys come and go.
+// The sorted keys are taken once when a scan starts (cursor 0) and shared by the calls that
+// follow, so a full scan costs one sort instead of one per call. A snapshot taken after a scan
+// started still holds every key that existed throughout it, so concurrent scans can share one.
+func (s *Server) scan(args [][]byte, w writer) error {
+	cursor, err := strconv.ParseUint(string(args[1]), 10, 64)
+	if err != nil {
+		return w.err("ERR invalid cursor")
+	}
+	pattern, count := "*", defaultScanCount
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return w.err("ERR syntax error")
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			count, err = strconv.Atoi(string(args[i+1]))
+			if err != nil || count < 1 {
+				return w.err("ERR syntax error")
+			}
+		default:
+			return w.err("ERR syntax error")
+		}
+	}
+
+	keys := s.scanSnapshot(cursor == 0)
+	start := sort.Search(len(keys), func(i int) bool { return keys[i].hash >= cursor })
+	remaining := keys[start:]
+
+	// Visit count keys, plus any that share the last key's hash so none are skipped
+	n := min(count, len(remaining))
+	for n > 0 && n < len(remaining) && remaining[n].hash == remaining[n-1].hash {
+		n++
+	}
+	var next uint64 // 0 tells the client the scan is complete
+	if n < len(remaining) {
+		next = remaining[n-1].hash + 1
+	}
+
+	var matched []string
+	for _, hk := range remaining[:n] {
+		if !matchGlob(pattern, hk.key) {
+			continue
+		}
+		if _, exists := s.cache.TTL(hk.key); exists { // Skip keys deleted since the snapshot
+			matched = append(matched, hk.key)
+		}
+	}
+	w.arrayHeader(2)
+	w.bulk([]byte(strconv.FormatUint(next, 10)))
+	w.arrayHeader(len(matched))
+	for _, key := range matched {
+		w.bulk([]byte(key))
+	}
+	return nil
+}
+
+// scanSnapshot returns the keys sorted by scanHash, taking a new snapshot if fresh is set.
+// The returned slice is never modified afterwards.
+func (s *Server) scanSnapshot(fresh bool) []hashedKey {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	if fresh || s.scanKeys == nil {
+		live := s.cache.Keys()
+		keys := make([]hashedKey, len(live))
+		for i, key := range live {
+			keys[i] = hashedKey{scanHash(key), key}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].hash != keys[j].hash {
+				return keys[i].hash < keys[j].hash
+			}
+			return keys[i].key < keys[j].key
+		})
+		s.scanKeys = keys
+	}
+	return s.scanKeys
+}
+
+// scanHash orders keys for SCAN
+func scanHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// deadline turns a TTL into an Item deadline; 0 means no expiry
+func deadline(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
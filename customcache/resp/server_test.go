@@ -0,0 +1,200 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go-ex/customcache"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer serves a fresh cache on a loopback port and returns a client connected to it
+func startServer(t *testing.T, config ServerConfig) (*Client, string) {
+	t.Helper()
+	return serveCache(t, customcache.NewConcurrentCache(), config)
+}
+
+// serveCache serves cache on a loopback port and returns a client connected to it
+func serveCache(t *testing.T, cache *customcache.ConcurrentCache, config ServerConfig) (*Client, string) {
+	t.Helper()
+	server := NewServerWithConfig(cache, config)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+	client, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+		cache.Close()
+	})
+	return client, l.Addr().String()
+}
+
+func TestClientCommands(t *testing.T) {
+	client, _ := startServer(t, ServerConfig{})
+
+	if err := client.Set("greeting", []byte("hello"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.Get("greeting"); err != nil || string(value) != "hello" {
+		t.Fatalf("Get(greeting) = %q, %v", value, err)
+	}
+	if ttl, err := client.TTL("greeting"); err != nil || ttl != 60 {
+		t.Fatalf("TTL(greeting) = %d, %v", ttl, err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		if n, err := client.Incr("counter"); err != nil || n != want {
+			t.Fatalf("Incr(counter) = %d, %v, want %d", n, err, want)
+		}
+	}
+	if n, err := client.Del("greeting", "counter", "missing"); err != nil || n != 2 {
+		t.Fatalf("Del = %d, %v, want 2", n, err)
+	}
+	if _, err := client.Get("greeting"); !errors.Is(err, ErrNil) {
+		t.Fatalf("Get of a deleted key: %v, want ErrNil", err)
+	}
+	if _, err := client.Do("SET", "k", "v", "NX", "XX"); err == nil {
+		t.Fatal("SET NX XX was accepted")
+	}
+}
+
+func TestScanReturnsEveryKey(t *testing.T) {
+	client, _ := startServer(t, ServerConfig{})
+	want := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		want = append(want, key)
+		if err := client.Set(key, []byte("x"), 0); err != nil {
+			t.Fatal(err)
+		}
+		client.Set(fmt.Sprintf("order:%d", i), []byte("x"), 0)
+	}
+
+	got, err := client.Scan("user:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Scan(user:*) returned %d keys, want %d", len(got), len(want))
+	}
+}
+
+func TestServerRejectsOversizedBulk(t *testing.T) {
+	client, addr := startServer(t, ServerConfig{MaxBulkBytes: 1024})
+
+	if err := client.Set("small", []byte(strings.Repeat("x", 1024)), 0); err != nil {
+		t.Fatalf("Set of a value at the limit: %v", err)
+	}
+
+	// A header promising more than the limit is refused before any payload is read
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "*3\r\n$3\r\nSET\r\n$3\r\nbig\r\n$%d\r\n", 1<<30)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasPrefix(reply, "-ERR Protocol error") {
+		t.Fatalf("reply = %q, %v, want a protocol error", reply, err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"*", "", true},
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"*:1", "user/a:1", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"*a*a*a*a*a*a*a*a*b", strings.Repeat("a", 40), false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %t, want %t", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlobIsNotExponential(t *testing.T) {
+	pattern := strings.Repeat("*a", 30) + "b"
+	key := strings.Repeat("a", 5000)
+	start := time.Now()
+	if matchGlob(pattern, key) {
+		t.Fatal("pattern matched")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("matchGlob took %v", elapsed)
+	}
+}
+
+func TestServerRejectsOverflowingExpireTimes(t *testing.T) {
+	client, _ := startServer(t, ServerConfig{})
+	client.Set("k", []byte("v"), 0)
+
+	tests := [][]string{
+		{"SET", "k", "v", "EX", "9223372037"}, // Just over math.MaxInt64 nanoseconds
+		{"SET", "k", "v", "PX", "9223372036855"},
+		{"SET", "k", "v", "EX", "9223372036854775807"},
+		{"EXPIRE", "k", "9223372037"},
+	}
+	for _, args := range tests {
+		_, err := client.Do(args...)
+		var serverErr Error
+		if !errors.As(err, &serverErr) || !strings.Contains(string(serverErr), "invalid expire time") {
+			t.Fatalf("%s = %v, want an invalid expire time error", strings.Join(args, " "), err)
+		}
+	}
+	if ttl, err := client.TTL("k"); err != nil || ttl != -1 {
+		t.Fatalf("TTL(k) = %d, %v after rejected expire times, want -1", ttl, err)
+	}
+
+	// The largest TTLs that fit are still accepted
+	if _, err := client.Do("SET", "k", "v", "EX", "9223372036"); err != nil {
+		t.Fatalf("SET EX at the limit: %v", err)
+	}
+	if _, err := client.Do("EXPIRE", "k", "9223372036"); err != nil {
+		t.Fatalf("EXPIRE at the limit: %v", err)
+	}
+}
+
+func TestIncrReportsRejectedWrites(t *testing.T) {
+	cache := customcache.NewConcurrentCacheWithConfig(customcache.ConcurrentCacheConfig{MaxEntryBytes: 9, Quiet: true})
+	client, _ := serveCache(t, cache, ServerConfig{})
+
+	if err := client.Set("counter", []byte("98"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := client.Incr("counter"); err != nil || n != 99 {
+		t.Fatalf("Incr(counter) = %d, %v, want 99", n, err)
+	}
+	// "100" makes the entry 10 bytes, over MaxEntryBytes
+	if n, err := client.Incr("counter"); err == nil {
+		t.Fatalf("Incr(counter) = %d, want an error for the rejected write", n)
+	}
+	if value, err := client.Get("counter"); err != nil || string(value) != "99" {
+		t.Fatalf("Get(counter) = %q, %v, want 99", value, err)
+	}
+}
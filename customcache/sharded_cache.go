@@ -0,0 +1,220 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ex/pkg/cachestats"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConcurrentCache guards a single map with a single RWMutex, so every Set serializes across all cores.
+// ShardedCache splits the keys over several ConcurrentCaches, each with its own lock,
+// so writes to different shards no longer contend with each other.
+
+// ShardedCache is a ConcurrentCache split into a power-of-two number of independently locked shards
+type ShardedCache struct {
+	shards []*ConcurrentCache
+	mask   uint64 // len(shards)-1, used instead of a modulo to pick a shard
+}
+
+// NewShardedCache creates a ShardedCache with at least the given number of shards, rounded up to a
+// power of two. Every shard is created with the same config, except that MaxBytes and MaxEntries
+// are split evenly between the shards and each shard gets its own SnapshotPath and AOFPath (see
+// shardPath).
+func NewShardedCache(shards int, config ConcurrentCacheConfig) *ShardedCache {
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	c := &ShardedCache{
+		shards: make([]*ConcurrentCache, n),
+		mask:   uint64(n - 1),
+	}
+	if config.MaxBytes > 0 {
+		config.MaxBytes = max(config.MaxBytes/int64(n), 1) // Dividing must not turn the limit off
+	}
+	if config.MaxEntries > 0 {
+		config.MaxEntries = max(config.MaxEntries/n, 1)
+	}
+	for i := range c.shards {
+		shardConfig := config
+		shardConfig.SnapshotPath = shardPath(config.SnapshotPath, i, n)
+		shardConfig.AOFPath = shardPath(config.AOFPath, i, n)
+		c.shards[i] = NewConcurrentCacheWithConfig(shardConfig)
+	}
+	return c
+}
+
+// shardPath gives shard i of n its own file next to path: "cache.aof" becomes "cache.3-of-16.aof".
+// The shard count is part of the name because a key's shard depends on it, so files written with
+// a different count are not picked up.
+func shardPath(path string, i, n int) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d-of-%d%s", strings.TrimSuffix(path, ext), i, n, ext)
+}
+
+// fnv64a hashes the key with FNV-1a without allocating
+func fnv64a(key string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	hash := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+	return hash
+}
+
+// shard returns the shard that owns key
+func (c *ShardedCache) shard(key string) *ConcurrentCache {
+	return c.shards[fnv64a(key)&c.mask]
+}
+
+// Set adds or updates a key-value pair in the cache
+func (c *ShardedCache) Set(key string, value interface{}) {
+	c.shard(key).Set(key, value)
+}
+
+// SetWithTTL adds or updates a key-value pair that expires after ttl (ttl <= 0 never expires)
+func (c *ShardedCache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.shard(key).SetWithTTL(key, value, ttl)
+}
+
+// Get retrieves a value from the cache
+func (c *ShardedCache) Get(key string) (interface{}, bool) {
+	return c.shard(key).Get(key)
+}
+
+// GetItem retrieves a value from the cache together with its metadata
+func (c *ShardedCache) GetItem(key string) (Item, bool) {
+	return c.shard(key).GetItem(key)
+}
+
+// Delete removes a key-value pair from the cache
+func (c *ShardedCache) Delete(key string) {
+	c.shard(key).Delete(key)
+}
+
+// GetOrLoad returns the cached value for key, loading it on a miss (see ConcurrentCache.GetOrLoad)
+func (c *ShardedCache) GetOrLoad(ctx context.Context, key string, loader Loader) (interface{}, error) {
+	return c.shard(key).GetOrLoad(ctx, key, loader)
+}
+
+// CompareAndSwap replaces the value of key with newValue if it currently equals oldValue
+func (c *ShardedCache) CompareAndSwap(key string, oldValue, newValue interface{}) bool {
+	return c.shard(key).CompareAndSwap(key, oldValue, newValue)
+}
+
+// CompareAndDelete deletes key if its current value equals oldValue
+func (c *ShardedCache) CompareAndDelete(key string, oldValue interface{}) bool {
+	return c.shard(key).CompareAndDelete(key, oldValue)
+}
+
+// GetOrSet returns the existing value of key, or stores and returns value
+func (c *ShardedCache) GetOrSet(key string, value interface{}) (interface{}, bool) {
+	return c.shard(key).GetOrSet(key, value)
+}
+
+// Update atomically replaces the value of key with fn(old, found) (see ConcurrentCache.Update).
+// fn holds the lock of key's whole shard, not just of key.
+func (c *ShardedCache) Update(key string, fn func(old interface{}, found bool) interface{}) (interface{}, bool) {
+	return c.shard(key).Update(key, fn)
+}
+
+// SetWithTags adds or updates a key-value pair labelled with tags
+func (c *ShardedCache) SetWithTags(key string, value interface{}, tags ...string) {
+	c.shard(key).SetWithTags(key, value, tags...)
+}
+
+// InvalidateTag removes every key tagged with tag from all shards and returns how many were removed
+func (c *ShardedCache) InvalidateTag(tag string) int {
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.InvalidateTag(tag)
+	}
+	return removed
+}
+
+// DeletePrefix removes every key starting with prefix from all shards and returns how many were removed
+func (c *ShardedCache) DeletePrefix(prefix string) int {
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.DeletePrefix(prefix)
+	}
+	return removed
+}
+
+// Keys returns a snapshot of every key that has not expired in any shard, in no particular order.
+// The shards are read one after another, so it is not a single point-in-time view.
+func (c *ShardedCache) Keys() []string {
+	var keys []string
+	for _, shard := range c.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Stats returns the counters of all shards added together
+func (c *ShardedCache) Stats() cachestats.Stats {
+	stats := make([]cachestats.Stats, len(c.shards))
+	for i, shard := range c.shards {
+		stats[i] = shard.Stats()
+	}
+	return cachestats.Merge(stats...)
+}
+
+// ResetStats zeroes the counters of every shard
+func (c *ShardedCache) ResetStats() {
+	for _, shard := range c.shards {
+		shard.ResetStats()
+	}
+}
+
+// Flush writes all pending write-behind writes of every shard to the store
+func (c *ShardedCache) Flush() error {
+	var errs []error
+	for _, shard := range c.shards {
+		errs = append(errs, shard.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every shard
+func (c *ShardedCache) Close() error {
+	var errs []error
+	for _, shard := range c.shards {
+		errs = append(errs, shard.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func RunShardedCache() {
+	cache := NewShardedCache(16, ConcurrentCacheConfig{Quiet: true})
+	var wg sync.WaitGroup
+
+	// Writers on different shards no longer wait for each other
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprintf("user:%d", worker*1000+j)
+				cache.Set(key, j)
+				cache.Get(key)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	fmt.Printf("Shards: %d\n", len(cache.shards))
+	fmt.Printf("Stats: %s\n", cache.Stats())
+}
@@ -0,0 +1,59 @@
+package customcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestShardedCacheSplitsMaxEntries(t *testing.T) {
+	cache := NewShardedCache(4, ConcurrentCacheConfig{MaxEntries: 100})
+	defer cache.Close()
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key:%d", i), i)
+	}
+	if size := cache.Stats().Size; size > 100 {
+		t.Fatalf("cache holds %d entries, MaxEntries is 100", size)
+	}
+}
+
+func TestShardedCacheGivesEachShardItsOwnFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := ConcurrentCacheConfig{
+		SnapshotPath: filepath.Join(dir, "cache.snapshot"),
+		AOFPath:      filepath.Join(dir, "cache.aof"),
+	}
+	cache := NewShardedCache(4, config)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		cache.Set(key, key)
+	}
+	cache.SetWithTTL("session", "token", time.Minute)
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		for _, path := range []string{config.SnapshotPath, config.AOFPath} {
+			if _, err := os.Stat(shardPath(path, i, 4)); err != nil {
+				t.Fatalf("shard %d: %v", i, err)
+			}
+		}
+	}
+	if _, err := os.Stat(config.AOFPath); !os.IsNotExist(err) {
+		t.Fatalf("shards wrote to the shared path %s", config.AOFPath)
+	}
+
+	restarted := NewShardedCache(4, config)
+	defer restarted.Close()
+	keys := restarted.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[a b c d e f session]"; got != want {
+		t.Fatalf("Keys() = %s, want %s", got, want)
+	}
+	if item, ok := restarted.GetItem("session"); !ok || item.Value != "token" || item.ExpiresAt.IsZero() {
+		t.Fatalf("GetItem(session) = %+v, %t", item, ok)
+	}
+}
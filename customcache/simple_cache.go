@@ -1,13 +1,18 @@
 package customcache
 
-import "fmt"
+import (
+	"fmt"
+	"go-ex/pkg/cachestats"
+)
 
 // You have a backend service that frequently reads data from a database. To reduce the load on the database and improve response times for common requests, you decide to implement an in-memory cache.
 // Describe how you would design a simple in-memory key-value cache in Go to store data retrieved from the database.
 
 // Cache stores key-value pairs
 type SimpleCache struct {
-	data map[string]interface{} // Using interface{} to store any type of data
+	data  map[string]interface{} // Using interface{} to store any type of data
+	stats cachestats.Counters
+	Quiet bool // Don't print every cache operation (they are printed by default)
 }
 
 // NewSimpleCache creates a new instance of SimpleCache
@@ -20,20 +25,41 @@ func NewSimpleCache() *SimpleCache {
 // Set adds or updates a key-value pair in the cache
 func (c *SimpleCache) Set(key string, value interface{}) {
 	c.data[key] = value
-	fmt.Printf("Cache: Set key '%s'\n", key)
+	c.stats.Set()
+	c.logf("Cache: Set key '%s'\n", key)
 }
 
 // Get retrieves a value from the cache
 func (c *SimpleCache) Get(key string) (interface{}, bool) {
 	value, found := c.data[key]
-	fmt.Printf("Cache: Get key '%s' - Found: %t\n", key, found)
+	if found {
+		c.stats.Hit()
+	} else {
+		c.stats.Miss()
+	}
+	c.logf("Cache: Get key '%s' - Found: %t\n", key, found)
 	return value, found
 }
 
 // Delete removes a key-value pair from the cache
 func (c *SimpleCache) Delete(key string) {
-	delete(c.data, key)
-	fmt.Printf("Cache: Deleted key '%s'\n", key)
+	if _, found := c.data[key]; found {
+		delete(c.data, key)
+		c.stats.Delete()
+	}
+	c.logf("Cache: Deleted key '%s'\n", key)
+}
+
+// Stats returns a snapshot of the cache's hit/miss counters and current size
+func (c *SimpleCache) Stats() cachestats.Stats {
+	return c.stats.Snapshot(len(c.data))
+}
+
+// logf prints a cache operation unless Quiet is set
+func (c *SimpleCache) logf(format string, args ...interface{}) {
+	if !c.Quiet {
+		fmt.Printf(format, args...)
+	}
 }
 
 func RunSimpleCache() {
@@ -47,4 +73,5 @@ func RunSimpleCache() {
 
 	cache.Delete("user:123")
 	_, found = cache.Get("user:123") // Should not be found now
+	fmt.Printf("Stats: %s\n", cache.Stats())
 }
@@ -0,0 +1,77 @@
+package customcache
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// Throughput of SlabCache under the same read/write mixes as BenchmarkMixedConcurrentCache:
+//
+//	go test -bench 'Mixed(Concurrent|Slab)Cache' ./customcache
+
+type slabBench struct{ c *SlabCache }
+
+func (b slabBench) set(key string, value interface{}) { b.c.Set(key, []byte(value.(string))) }
+func (b slabBench) get(key string)                    { b.c.Get(key) }
+
+func BenchmarkMixedSlabCache(b *testing.B) {
+	benchmarkReadMixes(b, func() benchCache { return slabBench{NewSlabCache(SlabCacheConfig{})} })
+}
+
+// The GC cost of holding many entries in ConcurrentCache and in SlabCache. Each cache is filled,
+// then every iteration is one forced collection: its wall time (ns/op) is dominated by marking,
+// which grows with the number of pointers on the heap, while the stop-the-world pauses come from
+// runtime.MemStats:
+//
+//	go test -run NONE -bench GCWithCachedEntries -benchtime 5x ./customcache
+
+const gcBenchmarkEntries = 1_000_000
+
+func BenchmarkGCWithCachedEntries(b *testing.B) {
+	value := make([]byte, 64)
+	keys := make([]string, gcBenchmarkEntries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	caches := []struct {
+		name string
+		fill func() interface{}
+	}{
+		{"ConcurrentCache", func() interface{} {
+			c := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Quiet: true})
+			for _, key := range keys {
+				c.Set(key, append([]byte(nil), value...))
+			}
+			return c
+		}},
+		{"SlabCache", func() interface{} {
+			c := NewSlabCache(SlabCacheConfig{MaxBytes: 256 << 20}) // Room for every entry
+			for _, key := range keys {
+				c.Set(key, value)
+			}
+			return c
+		}},
+	}
+	for _, cache := range caches {
+		b.Run(cache.name, func(b *testing.B) {
+			filled := cache.fill()
+			runtime.GC()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+			}
+			b.StopTimer()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(filled)
+
+			collections := max(after.NumGC-before.NumGC, 1)
+			b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(collections), "stw-ns/gc")
+			b.ReportMetric(float64(after.HeapObjects), "heap-objects")
+			b.ReportMetric(float64(after.HeapAlloc>>20), "heap-MB")
+		})
+	}
+}
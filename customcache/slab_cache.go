@@ -0,0 +1,317 @@
+package customcache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"go-ex/pkg/cachestats"
+	"sync"
+	"time"
+)
+
+// With millions of entries, ConcurrentCache's map of strings and interfaces is millions of pointers
+// the GC has to trace on every cycle. SlabCache (in the style of bigcache) keeps serialized entries
+// in one large preallocated byte ring per shard and indexes them with a map[uint64]uint32 from key
+// hash to ring offset. Neither holds a pointer, so the GC skips the entries entirely.
+//
+// Entries are only ever appended. Overwritten and deleted entries stay in the ring as garbage until
+// the write position wraps around to them; whatever is still live there is evicted (FIFO, not LRU).
+// All entries share one TTL, so the oldest entries are also the first to expire.
+//
+// Entry layout in the ring:
+//
+//	[8 byte written-at unix nanos][8 byte key hash][2 byte key length][4 byte value length][key][value]
+
+const slabHeaderSize = 8 + 8 + 2 + 4
+
+// SlabCacheConfig holds the SlabCache settings
+type SlabCacheConfig struct {
+	Shards          int           // Number of shards, rounded up to a power of two (default 64)
+	MaxBytes        int           // Total ring size, split evenly between the shards (default 64 MB)
+	TTL             time.Duration // How long entries live (0 means until they are overwritten)
+	CleanupInterval time.Duration // How often expired entries are dropped from the rings (0 disables)
+}
+
+// SlabCache is a sharded cache of []byte values stored in preallocated byte rings
+type SlabCache struct {
+	shards []*slabShard
+	mask   uint64
+	config SlabCacheConfig
+
+	stats     cachestats.Counters
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// slabShard is one ring buffer with its index
+type slabShard struct {
+	mu    sync.RWMutex
+	index map[uint64]uint32 // Key hash -> offset of the newest entry for it
+	ring  []byte
+
+	// Live data is [head, tail) or, once the writes have wrapped, [head, end) followed by [0, tail)
+	head, tail, end uint32
+	wrapped         bool
+	entries         int // Entries in the ring, including overwritten and deleted ones
+}
+
+// NewSlabCache creates a SlabCache and preallocates all of its ring buffers
+func NewSlabCache(config SlabCacheConfig) *SlabCache {
+	if config.Shards <= 0 {
+		config.Shards = 64
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 64 << 20
+	}
+	n := 1
+	for n < config.Shards {
+		n <<= 1
+	}
+	shardBytes := min(config.MaxBytes/n, 1<<32-1) // Offsets are uint32
+	shardBytes = max(shardBytes, slabHeaderSize+1)
+
+	c := &SlabCache{
+		shards: make([]*slabShard, n),
+		mask:   uint64(n - 1),
+		config: config,
+		stop:   make(chan struct{}),
+	}
+	for i := range c.shards {
+		c.shards[i] = &slabShard{index: make(map[uint64]uint32), ring: make([]byte, shardBytes)}
+	}
+	if config.TTL > 0 && config.CleanupInterval > 0 {
+		go c.cleanupRoutine() // Start the expiry sweeper
+	}
+	return c
+}
+
+func (c *SlabCache) shard(hash uint64) *slabShard {
+	return c.shards[hash&c.mask]
+}
+
+// Set stores a copy of value under key. It returns ErrTooLarge if the entry doesn't fit in a shard.
+func (c *SlabCache) Set(key string, value []byte) error {
+	size := slabHeaderSize + len(key) + len(value)
+	hash := fnv64a(key)
+	s := c.shard(hash)
+	if len(key) > 1<<16-1 || size > len(s.ring) {
+		return fmt.Errorf("%w: key %q is %d bytes, shard size %d", ErrTooLarge, key, size, len(s.ring))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset := s.reserve(uint32(size), c)
+	entry := s.ring[offset : offset+uint32(size)]
+	binary.LittleEndian.PutUint64(entry[0:8], uint64(time.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(entry[8:16], hash)
+	binary.LittleEndian.PutUint16(entry[16:18], uint16(len(key)))
+	binary.LittleEndian.PutUint32(entry[18:22], uint32(len(value)))
+	copy(entry[slabHeaderSize:], key)
+	copy(entry[slabHeaderSize+len(key):], value)
+	s.index[hash] = offset
+	c.stats.Set()
+	return nil
+}
+
+// Get returns a copy of the value stored under key
+func (c *SlabCache) Get(key string) ([]byte, bool) {
+	hash := fnv64a(key)
+	s := c.shard(hash)
+	s.mu.RLock()
+	offset, ok := s.index[hash]
+	if !ok {
+		s.mu.RUnlock()
+		c.stats.Miss()
+		return nil, false
+	}
+	entry := s.entry(offset)
+	if s.key(entry) != key { // Another key with the same hash
+		s.mu.RUnlock()
+		c.stats.Miss()
+		return nil, false
+	}
+	if c.expired(entry, time.Now()) {
+		s.mu.RUnlock()
+		c.stats.Miss()
+		return nil, false // Dropped by the sweeper or once the ring wraps around to it
+	}
+	value := append([]byte(nil), s.value(entry)...) // The ring slot will be reused
+	s.mu.RUnlock()
+	c.stats.Hit()
+	return value, true
+}
+
+// Delete removes key. Its bytes are reclaimed when the ring wraps around to them.
+func (c *SlabCache) Delete(key string) {
+	hash := fnv64a(key)
+	s := c.shard(hash)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset, ok := s.index[hash]; ok && s.key(s.entry(offset)) == key {
+		delete(s.index, hash)
+		c.stats.Delete()
+	}
+}
+
+// Len returns the number of keys in the cache, including expired ones not yet swept
+func (c *SlabCache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		n += len(s.index)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Stats returns a snapshot of the counters; Bytes is the ring space in use, garbage included
+func (c *SlabCache) Stats() cachestats.Stats {
+	size, bytes := 0, int64(0)
+	for _, s := range c.shards {
+		s.mu.RLock()
+		size += len(s.index)
+		bytes += int64(s.used())
+		s.mu.RUnlock()
+	}
+	stats := c.stats.Snapshot(size)
+	stats.Bytes = bytes
+	return stats
+}
+
+// Close stops the expiry sweeper
+func (c *SlabCache) Close() {
+	c.closeOnce.Do(func() { close(c.stop) })
+}
+
+// expired reports whether an entry is past the cache's TTL
+func (c *SlabCache) expired(entry []byte, now time.Time) bool {
+	if c.config.TTL <= 0 {
+		return false
+	}
+	written := int64(binary.LittleEndian.Uint64(entry[0:8]))
+	return now.UnixNano()-written >= int64(c.config.TTL)
+}
+
+// entry returns the entry starting at offset
+func (s *slabShard) entry(offset uint32) []byte {
+	keyLen := uint32(binary.LittleEndian.Uint16(s.ring[offset+16:]))
+	valueLen := binary.LittleEndian.Uint32(s.ring[offset+18:])
+	return s.ring[offset : offset+slabHeaderSize+keyLen+valueLen]
+}
+
+func (s *slabShard) key(entry []byte) string {
+	keyLen := int(binary.LittleEndian.Uint16(entry[16:18]))
+	return string(entry[slabHeaderSize : slabHeaderSize+keyLen])
+}
+
+func (s *slabShard) value(entry []byte) []byte {
+	keyLen := int(binary.LittleEndian.Uint16(entry[16:18]))
+	return entry[slabHeaderSize+keyLen:]
+}
+
+// used returns the number of ring bytes holding entries
+func (s *slabShard) used() uint32 {
+	if s.wrapped {
+		return s.end - s.head + s.tail
+	}
+	return s.tail - s.head
+}
+
+// reserve finds room for size bytes at the write position, evicting the oldest entries until it
+// fits, and returns the offset to write at. Called with s.mu held.
+func (s *slabShard) reserve(size uint32, c *SlabCache) uint32 {
+	for {
+		switch {
+		case s.entries == 0:
+			s.head, s.tail, s.wrapped = 0, 0, false
+			fallthrough
+		case !s.wrapped && s.tail+size <= uint32(len(s.ring)):
+			offset := s.tail
+			s.tail += size
+			s.entries++
+			return offset
+		case !s.wrapped && size <= s.head:
+			// Not enough room at the end: leave it unused and continue at the start of the ring
+			s.end, s.wrapped = s.tail, true
+			s.tail = size
+			s.entries++
+			return 0
+		case s.wrapped && s.tail+size <= s.head:
+			offset := s.tail
+			s.tail += size
+			s.entries++
+			return offset
+		default:
+			s.evictOldest(c, time.Now())
+		}
+	}
+}
+
+// evictOldest drops the entry at the head of the ring. Called with s.mu held.
+func (s *slabShard) evictOldest(c *SlabCache, now time.Time) {
+	offset := s.head
+	entry := s.entry(offset)
+	hash := binary.LittleEndian.Uint64(entry[8:16])
+	if current, ok := s.index[hash]; ok && current == offset { // Still the live copy of its key
+		delete(s.index, hash)
+		if c.expired(entry, now) {
+			c.stats.Expire()
+		} else {
+			c.stats.Evict()
+		}
+	}
+
+	s.head += uint32(len(entry))
+	s.entries--
+	if s.wrapped && s.head == s.end {
+		s.head, s.wrapped = 0, false // The upper part is used up; live data is [0, tail) now
+	}
+}
+
+// deleteExpired drops expired entries from the head of every ring. Since all entries share one TTL,
+// the sweep can stop at the first entry that is still fresh.
+func (c *SlabCache) deleteExpired() {
+	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for s.entries > 0 && c.expired(s.entry(s.head), now) {
+			s.evictOldest(c, now)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// cleanupRoutine periodically sweeps expired entries until the cache is closed
+func (c *SlabCache) cleanupRoutine() {
+	ticker := time.NewTicker(c.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.deleteExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func RunSlabCache() {
+	// 4 shards of 1 KB each, so the rings wrap around quickly
+	cache := NewSlabCache(SlabCacheConfig{Shards: 4, MaxBytes: 4 << 10, TTL: time.Millisecond * 100, CleanupInterval: time.Millisecond * 50})
+	defer cache.Close()
+
+	cache.Set("user:1", []byte("Alice"))
+	cache.Set("user:1", []byte("Alicia")) // The old entry stays in the ring as garbage
+	value, found := cache.Get("user:1")
+	fmt.Printf("user:1=%s found=%t\n", value, found)
+
+	for i := 0; i < 200; i++ {
+		cache.Set(fmt.Sprintf("item:%d", i), make([]byte, 64)) // Far more than fits
+	}
+	_, found = cache.Get("item:0")
+	fmt.Printf("item:0 found after wraparound: %t\n", found)
+	fmt.Printf("Stats: %s\n", cache.Stats())
+
+	time.Sleep(time.Millisecond * 200) // Let everything expire and the sweeper run
+	fmt.Printf("After TTL: %s\n", cache.Stats())
+}
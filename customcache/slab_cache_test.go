@@ -0,0 +1,221 @@
+package customcache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSlabCache returns a single-shard SlabCache with a ring of ringBytes
+func newTestSlabCache(ringBytes int, ttl time.Duration) (*SlabCache, *slabShard) {
+	c := NewSlabCache(SlabCacheConfig{Shards: 1, MaxBytes: ringBytes, TTL: ttl})
+	return c, c.shards[0]
+}
+
+// checkRing walks the ring from head to tail and verifies the entry count, the bounds and that
+// every indexed offset is the start of an entry for its hash
+func checkRing(t *testing.T, s *slabShard) {
+	t.Helper()
+	starts := make(map[uint32]uint64)
+	walk := func(from, to uint32) {
+		for offset := from; offset < to; {
+			entry := s.entry(offset)
+			starts[offset] = binary.LittleEndian.Uint64(entry[8:16])
+			offset += uint32(len(entry))
+			if offset > to {
+				t.Fatalf("entry at %d runs past %d", offset-uint32(len(entry)), to)
+			}
+		}
+	}
+	if s.wrapped {
+		if s.tail > s.head || s.end > uint32(len(s.ring)) {
+			t.Fatalf("wrapped ring with head %d, tail %d, end %d", s.head, s.tail, s.end)
+		}
+		walk(s.head, s.end)
+		walk(0, s.tail)
+	} else {
+		walk(s.head, s.tail)
+	}
+	if len(starts) != s.entries {
+		t.Fatalf("walked %d entries, shard counts %d", len(starts), s.entries)
+	}
+	for hash, offset := range s.index {
+		if h, ok := starts[offset]; !ok || h != hash {
+			t.Fatalf("index sends hash %x to offset %d, which is not its entry", hash, offset)
+		}
+	}
+}
+
+// entrySize is the ring space an entry takes
+func entrySize(key string, value []byte) int {
+	return slabHeaderSize + len(key) + len(value)
+}
+
+func TestSlabCacheWrapsAroundFIFO(t *testing.T) {
+	value := []byte("0123456789")
+	size := entrySize("k00", value)
+	c, s := newTestSlabCache(7*size+size/2, 0) // Seven entries and some slack at the end
+	defer c.Close()
+
+	for i := 0; i < 7; i++ {
+		c.Set(fmt.Sprintf("k%02d", i), value)
+	}
+	if s.wrapped || c.Stats().Evictions != 0 {
+		t.Fatalf("seven entries did not fit: wrapped %t, %s", s.wrapped, c.Stats())
+	}
+
+	// The eighth doesn't fit at the end: the oldest goes and writing resumes at offset 0
+	c.Set("k07", value)
+	checkRing(t, s)
+	if !s.wrapped || s.index[fnv64a("k07")] != 0 || s.end != uint32(7*size) {
+		t.Fatalf("after wrapping: wrapped %t, k07 at %d, end %d", s.wrapped, s.index[fnv64a("k07")], s.end)
+	}
+	if _, ok := c.Get("k00"); ok {
+		t.Fatal("k00 survived the wraparound")
+	}
+
+	// Keep writing: the ring always holds the seven newest keys
+	for i := 8; i < 100; i++ {
+		c.Set(fmt.Sprintf("k%02d", i), value)
+		checkRing(t, s)
+		for j := 0; j <= i; j++ {
+			_, ok := c.Get(fmt.Sprintf("k%02d", j))
+			if want := j > i-7; ok != want {
+				t.Fatalf("after writing k%02d: Get(k%02d) found %t, want %t", i, j, ok, want)
+			}
+		}
+	}
+	if stats := c.Stats(); stats.Evictions != 93 || stats.Size != 7 {
+		t.Fatalf("stats %s, want 93 evictions and 7 entries", stats)
+	}
+}
+
+func TestSlabCacheRandomSizes(t *testing.T) {
+	c, s := newTestSlabCache(1000, 0)
+	defer c.Close()
+	r := rand.New(rand.NewSource(8))
+	latest := make(map[string]string)
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("key:%d", r.Intn(50))
+		value := strings.Repeat(string(rune('a'+i%26)), r.Intn(200))
+		if err := c.Set(key, []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+		latest[key] = value
+		checkRing(t, s)
+		if s.used() > uint32(len(s.ring)) {
+			t.Fatalf("%d bytes used in a ring of %d", s.used(), len(s.ring))
+		}
+		for key, want := range latest {
+			if got, ok := c.Get(key); ok && string(got) != want {
+				t.Fatalf("Get(%s) = %q, want its last value %q", key, got, want)
+			}
+		}
+	}
+}
+
+func TestSlabCacheOverwrite(t *testing.T) {
+	c, s := newTestSlabCache(200, 0)
+	defer c.Close()
+	c.Set("k", []byte("first"))
+	c.Set("k", []byte("second"))
+	if value, ok := c.Get("k"); !ok || string(value) != "second" {
+		t.Fatalf("Get(k) = %q, %t", value, ok)
+	}
+	if stats := c.Stats(); stats.Size != 1 || stats.Bytes != int64(entrySize("k", []byte("first"))+entrySize("k", []byte("second"))) {
+		t.Fatalf("stats %s: want one key, with the stale copy still taking ring space", stats)
+	}
+
+	// Fill the rest of the ring, then wrap: the write at offset 0 needs exactly the stale copy's space
+	used := entrySize("k", []byte("first")) + entrySize("k", []byte("second"))
+	c.Set("f", make([]byte, 200-used-slabHeaderSize-len("f")))
+	c.Set("g", []byte("12345"))
+	checkRing(t, s)
+	if !s.wrapped || s.index[fnv64a("g")] != 0 {
+		t.Fatalf("g was not written at the start of the ring")
+	}
+	if value, ok := c.Get("k"); !ok || string(value) != "second" {
+		t.Fatalf("Get(k) = %q, %t after the stale copy was reclaimed", value, ok)
+	}
+	if stats := c.Stats(); stats.Evictions != 0 {
+		t.Fatalf("reclaiming a stale copy counted %d evictions", stats.Evictions)
+	}
+
+	c.Delete("k")
+	if _, ok := c.Get("k"); ok || c.Len() != 2 {
+		t.Fatalf("Get(k) found it after Delete, Len() = %d", c.Len())
+	}
+}
+
+func TestSlabCacheHashCollision(t *testing.T) {
+	c, s := newTestSlabCache(200, 0)
+	defer c.Close()
+	c.Set("owner", []byte("v"))
+
+	// A real 64-bit FNV collision is too rare to find, so plant one: "other" now hashes into
+	// the same index slot as "owner" as far as the shard can tell
+	s.index[fnv64a("other")] = s.index[fnv64a("owner")]
+	if value, ok := c.Get("other"); ok {
+		t.Fatalf("Get(other) = %q, the value of the key it collides with", value)
+	}
+	c.Delete("other")
+	if _, ok := c.Get("owner"); !ok {
+		t.Fatal("Delete(other) removed the key it collides with")
+	}
+	if stats := c.Stats(); stats.Deletes != 0 || stats.Misses != 1 {
+		t.Fatalf("stats %s, want the collision counted as a miss and no delete", stats)
+	}
+}
+
+func TestSlabCacheTTL(t *testing.T) {
+	c, s := newTestSlabCache(200, time.Minute)
+	defer c.Close()
+	c.Set("old", []byte("v"))
+	c.Set("new", []byte("v"))
+
+	// Backdate old's written-at stamp instead of waiting a minute
+	entry := s.entry(s.index[fnv64a("old")])
+	binary.LittleEndian.PutUint64(entry[0:8], uint64(time.Now().Add(-time.Minute).UnixNano()))
+	if _, ok := c.Get("old"); ok {
+		t.Fatal("Get(old) found an entry past the TTL")
+	}
+	if _, ok := c.Get("new"); !ok {
+		t.Fatal("Get(new) missed a fresh entry")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d; expired entries stay until they are swept", c.Len())
+	}
+
+	c.deleteExpired()
+	checkRing(t, s)
+	if stats := c.Stats(); stats.Size != 1 || stats.Expirations != 1 || stats.Evictions != 0 {
+		t.Fatalf("stats %s after the sweep, want one expiration", stats)
+	}
+}
+
+func TestSlabCacheEntryLargerThanShard(t *testing.T) {
+	c, s := newTestSlabCache(100, 0)
+	defer c.Close()
+	c.Set("small", []byte("v"))
+
+	err := c.Set("big", make([]byte, 100-slabHeaderSize-len("big")+1))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Set of an entry one byte over the shard = %v, want ErrTooLarge", err)
+	}
+	if _, ok := c.Get("small"); !ok || c.Stats().Sets != 1 {
+		t.Fatal("a rejected entry evicted others or was counted")
+	}
+
+	// An entry of exactly the shard size fits, after evicting everything else
+	if err := c.Set("big", make([]byte, 100-slabHeaderSize-len("big"))); err != nil {
+		t.Fatal(err)
+	}
+	checkRing(t, s)
+	if _, ok := c.Get("big"); !ok || c.Len() != 1 {
+		t.Fatalf("Get(big) missed or Len() = %d, want only big", c.Len())
+	}
+}
@@ -0,0 +1,173 @@
+package customcache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Restarting a service throws away the whole cache and hammers the DB.
+// Snapshots let a cache be saved to disk and warm-started from it on the next boot.
+//
+// Format: a gob stream of one snapshotHeader followed by one snapshotRecord per item.
+// Deadlines are stored as absolute times, so TTLs keep counting down while the process is down.
+// Values of custom types must be registered with gob.Register.
+
+const (
+	snapshotMagic   = "customcache-snapshot"
+	snapshotVersion = 1
+)
+
+// ErrBadSnapshot is returned when a snapshot is not in a format this version understands
+var ErrBadSnapshot = errors.New("customcache: unrecognized snapshot format")
+
+// snapshotHeader starts every snapshot
+type snapshotHeader struct {
+	Magic   string
+	Version int
+	Count   int // Number of records that follow
+	SavedAt time.Time
+}
+
+// snapshotRecord is a single saved item
+type snapshotRecord struct {
+	Key       string
+	Value     interface{}
+	Flags     uint32
+	ExpiresAt time.Time
+	Tags      []string
+}
+
+// SaveTo writes a snapshot of every unexpired item to w
+func (c *ConcurrentCache) SaveTo(w io.Writer) error {
+	// Copy the items under the read lock, then encode without holding it
+	c.mu.RLock()
+	now := time.Now()
+	records := make([]snapshotRecord, 0, len(c.data))
+	for key, item := range c.data {
+		if !item.expired(now) {
+			records = append(records, snapshotRecord{Key: key, Value: item.Value, Flags: item.Flags, ExpiresAt: item.ExpiresAt, Tags: item.Tags})
+		}
+	}
+	c.mu.RUnlock()
+
+	enc := gob.NewEncoder(w)
+	header := snapshotHeader{Magic: snapshotMagic, Version: snapshotVersion, Count: len(records), SavedAt: now}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			return fmt.Errorf("customcache: snapshot key %q: %w", records[i].Key, err)
+		}
+	}
+	return nil
+}
+
+// LoadFrom adds the items of a snapshot read from r to the cache. Items that expired since the
+// snapshot was taken are skipped. Loaded items are not written to the BackingStore.
+func (c *ConcurrentCache) LoadFrom(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+	}
+	if header.Magic != snapshotMagic || header.Version < 1 || header.Version > snapshotVersion {
+		return fmt.Errorf("%w: magic %q version %d", ErrBadSnapshot, header.Magic, header.Version)
+	}
+
+	if header.Count < 0 {
+		return fmt.Errorf("%w: negative record count %d", ErrBadSnapshot, header.Count)
+	}
+
+	// Count comes from the file, so it only bounds the loop; records are appended as they decode
+	var records []snapshotRecord
+	for i := 0; i < header.Count; i++ {
+		var record snapshotRecord
+		if err := dec.Decode(&record); err != nil {
+			return fmt.Errorf("customcache: snapshot record %d of %d: %w", i+1, header.Count, err)
+		}
+		records = append(records, record)
+	}
+
+	// Only apply a snapshot that decoded completely
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for _, record := range records {
+		item := Item{Value: record.Value, Flags: record.Flags, Tags: record.Tags, ExpiresAt: record.ExpiresAt}
+		if item.expired(now) {
+			continue
+		}
+		item.CAS = c.cas.Add(1)
+		c.put(record.Key, item)
+	}
+	return nil
+}
+
+// SaveSnapshotFile writes a snapshot to path atomically (temp file plus rename)
+func (c *ConcurrentCache) SaveSnapshotFile(path string) error {
+	return writeFileAtomic(path, c.SaveTo)
+}
+
+// LoadSnapshotFile warm-starts the cache from the snapshot at path
+func (c *ConcurrentCache) LoadSnapshotFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.LoadFrom(f)
+}
+
+// snapshotRoutine saves a snapshot to SnapshotPath every SnapshotInterval until the cache is closed
+func (c *ConcurrentCache) snapshotRoutine() {
+	ticker := time.NewTicker(c.config.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.SaveSnapshotFile(c.config.SnapshotPath); err != nil {
+				fmt.Printf("Cache: snapshot to %s failed: %v\n", c.config.SnapshotPath, err)
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func RunSnapshot() {
+	dir, err := os.MkdirTemp("", "customcache-snapshot-")
+	if err != nil {
+		fmt.Printf("Failed to create snapshot directory: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.snapshot")
+
+	// The first "process" fills the cache; Close writes a final snapshot
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		SnapshotPath:     path,
+		SnapshotInterval: time.Second,
+	})
+	cache.Set("user:1", "Alice")
+	cache.SetWithTTL("session:1", "token-abc", time.Minute)
+	cache.SetWithTTL("otp:1", "123456", time.Millisecond*50)
+	cache.Close()
+
+	time.Sleep(time.Millisecond * 100) // otp:1 expires while "restarting"
+
+	// The restarted "process" warm-starts from the snapshot
+	restarted := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{SnapshotPath: path})
+	defer restarted.Close()
+	for _, key := range []string{"user:1", "session:1", "otp:1"} {
+		value, found := restarted.Get(key)
+		ttl, _ := restarted.TTL(key)
+		fmt.Printf("After restart: %s=%v found=%t ttl=%v\n", key, value, found, ttl.Round(time.Second))
+	}
+}
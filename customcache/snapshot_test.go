@@ -0,0 +1,109 @@
+package customcache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// encodeSnapshotHeader returns a snapshot stream holding only header
+func encodeSnapshotHeader(t *testing.T, header snapshotHeader) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(header); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadFromRejectsCorruptHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		wantBad bool // ErrBadSnapshot rather than a record decode error
+	}{
+		{"negative count", -1, true},
+		{"huge count", 1 << 40, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewConcurrentCache()
+			defer cache.Close()
+
+			data := encodeSnapshotHeader(t, snapshotHeader{Magic: snapshotMagic, Version: snapshotVersion, Count: tt.count})
+			err := cache.LoadFrom(bytes.NewReader(data))
+			if err == nil {
+				t.Fatal("LoadFrom accepted a corrupt header")
+			}
+			if got := errors.Is(err, ErrBadSnapshot); got != tt.wantBad {
+				t.Fatalf("errors.Is(err, ErrBadSnapshot) = %t, want %t (err: %v)", got, tt.wantBad, err)
+			}
+			if size := cache.Stats().Size; size != 0 {
+				t.Fatalf("cache has %d items after a failed load", size)
+			}
+		})
+	}
+}
+
+func TestLoadFromTruncatedSnapshot(t *testing.T) {
+	cache := NewConcurrentCache()
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, key)
+	}
+	var buf bytes.Buffer
+	if err := cache.SaveTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cache.Close()
+
+	restored := NewConcurrentCache()
+	defer restored.Close()
+	if err := restored.LoadFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-5])); err == nil {
+		t.Fatal("LoadFrom accepted a truncated snapshot")
+	}
+	if size := restored.Stats().Size; size != 0 {
+		t.Fatalf("cache has %d items after a failed load", size)
+	}
+}
+
+func TestWarmStartFromCorruptSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	data := encodeSnapshotHeader(t, snapshotHeader{Magic: snapshotMagic, Version: snapshotVersion, Count: -1})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Must start empty instead of crashing
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{SnapshotPath: path})
+	if size := cache.Stats().Size; size != 0 {
+		t.Fatalf("cache has %d items after a corrupt warm start", size)
+	}
+	cache.Close()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	cache := NewConcurrentCache()
+	cache.Set("user:1", "Alice")
+	cache.SetWithTTL("session:1", "token", time.Minute)
+	var buf bytes.Buffer
+	if err := cache.SaveTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cache.Close()
+
+	restored := NewConcurrentCache()
+	defer restored.Close()
+	if err := restored.LoadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := restored.Get("user:1"); !ok || value != "Alice" {
+		t.Fatalf("Get(user:1) = %v, %t", value, ok)
+	}
+	if ttl, ok := restored.TTL("session:1"); !ok || ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("TTL(session:1) = %v, %t", ttl, ok)
+	}
+}
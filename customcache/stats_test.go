@@ -0,0 +1,85 @@
+package customcache
+
+import (
+	"go-ex/pkg/cachestats"
+	"go-ex/pkg/sharedresource"
+	"testing"
+	"time"
+)
+
+func TestConcurrentCacheStats(t *testing.T) {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{MaxEntries: 2, Quiet: true})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("a", 2) // An update is a set too
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Delete("a")
+	cache.Delete("a") // Nothing left to delete
+	cache.Set("b", 1)
+	cache.Set("c", 1)
+	cache.Set("d", 1) // Evicts b, and e evicts c
+	cache.SetWithTTL("e", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	cache.Get("e") // Expired: removed and counted as a miss
+
+	want := cachestats.Stats{Hits: 1, Misses: 2, Sets: 6, Deletes: 1, Evictions: 2, Expirations: 1, Size: 1}
+	got := cache.Stats()
+	got.HitRatio, got.Bytes = 0, 0
+	if got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+
+	cache.ResetStats()
+	if stats := cache.Stats(); stats.Hits+stats.Misses+stats.Sets+stats.Deletes+stats.Evictions+stats.Expirations != 0 || stats.Size != 1 {
+		t.Fatalf("after ResetStats: %s, want zero counters and the size kept", stats)
+	}
+	cache.Get("d")
+	if stats := cache.Stats(); stats.Hits != 1 || stats.HitRatio != 1 {
+		t.Fatalf("counting after ResetStats: %s", stats)
+	}
+}
+
+func TestShardedCacheStats(t *testing.T) {
+	cache := NewShardedCache(4, ConcurrentCacheConfig{Quiet: true})
+	defer cache.Close()
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		cache.Set(key, key)
+		cache.Get(key)
+	}
+	cache.Get("missing")
+	if stats := cache.Stats(); stats.Sets != 6 || stats.Hits != 6 || stats.Misses != 1 || stats.Size != 6 {
+		t.Fatalf("Stats() = %s, want the shards added up", stats)
+	}
+	cache.ResetStats()
+	if stats := cache.Stats(); stats.Sets != 0 || stats.Hits != 0 || stats.Misses != 0 || stats.Size != 6 {
+		t.Fatalf("after ResetStats: %s", stats)
+	}
+}
+
+func TestSimpleCachesCountOperations(t *testing.T) {
+	simple := NewSimpleCache()
+	simple.Quiet = true
+	syncMap := &sharedresource.ConcurrentCache{Quiet: true}
+
+	simple.Set("a", 1)
+	syncMap.Store("a", 1)
+	simple.Set("a", 2)
+	syncMap.Store("a", 2)
+	simple.Get("a")
+	syncMap.Load("a")
+	simple.Get("b")
+	syncMap.Load("b")
+	simple.Delete("a")
+	syncMap.Delete("a")
+	simple.Delete("a")
+	syncMap.Delete("a")
+
+	want := cachestats.Stats{Hits: 1, Misses: 1, Sets: 2, Deletes: 1, HitRatio: 0.5}
+	for name, got := range map[string]cachestats.Stats{"SimpleCache": simple.Stats(), "sharedresource.ConcurrentCache": syncMap.Stats()} {
+		if got != want {
+			t.Errorf("%s stats = %+v, want %+v", name, got, want)
+		}
+	}
+}
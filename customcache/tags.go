@@ -0,0 +1,109 @@
+package customcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Related entries often have to go together: everything rendered for a user, every page of a product.
+// Items can carry tags, and InvalidateTag and DeletePrefix drop a whole group at once. Both look the
+// keys up in an index (tag -> keys, and a radix tree of keys) instead of scanning the map.
+
+// SetWithTags adds or updates a key-value pair labelled with tags, using DefaultTTL
+func (c *ConcurrentCache) SetWithTags(key string, value interface{}, tags ...string) {
+	c.SetItem(key, Item{Value: value, Tags: tags, ExpiresAt: expiresAt(c.config.DefaultTTL)})
+}
+
+// InvalidateTag removes every key tagged with tag and returns how many entries were removed
+func (c *ConcurrentCache) InvalidateTag(tag string) int {
+	match := func() []string {
+		keys := make([]string, 0, len(c.tags[tag]))
+		for key := range c.tags[tag] {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+	return c.deleteMatching(tag, match)
+}
+
+// DeletePrefix removes every key starting with prefix and returns how many entries were removed
+func (c *ConcurrentCache) DeletePrefix(prefix string) int {
+	return c.deleteMatching(prefix, func() []string { return c.keyIndex.withPrefix(prefix) })
+}
+
+// deleteMatching deletes the keys returned by match like Delete does (store, log, watchers) and
+// counts the live ones removed. match runs under the same write lock as the deletes, so no key can
+// be set or retagged in between. With a write-through Store the store deletes can't run under c.mu;
+// writeMu, which every writer takes in that mode, keeps them out from the match to the last delete
+// instead. name only labels log errors.
+func (c *ConcurrentCache) deleteMatching(name string, match func() []string) int {
+	var keys []string
+	writeThrough := c.config.Store != nil && c.config.WriteMode == WriteThrough
+	if writeThrough {
+		c.writeMu.Lock()
+		defer c.writeMu.Unlock()
+		c.mu.RLock()
+		matched := match()
+		c.mu.RUnlock()
+		for _, key := range matched {
+			if err := c.config.Store.Delete(context.Background(), key); err != nil {
+				c.storeFailed(key, err)
+				continue // Kept in the cache, like a failed Delete
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.unlockAndSync(name)
+	if !writeThrough {
+		keys = match()
+	}
+	removed := 0
+	for _, key := range keys {
+		if c.deleteLocked(key) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// tag adds key to the index of each of its tags. Called with c.mu held.
+func (c *ConcurrentCache) tag(key string, tags []string) {
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// untag removes key from the index of each of its tags. Called with c.mu held.
+func (c *ConcurrentCache) untag(key string, tags []string) {
+	for _, tag := range tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+func RunTagInvalidation() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{CleanupInterval: time.Minute})
+	defer cache.Close()
+
+	cache.SetWithTags("page:/users/1", "<h1>Alice</h1>", "user:1")
+	cache.SetWithTags("page:/users/1/orders", "<ul>...</ul>", "user:1", "orders")
+	cache.SetWithTags("page:/users/2", "<h1>Bob</h1>", "user:2")
+	cache.Set("session:1", "token-abc")
+	cache.Set("session:2", "token-def")
+	cache.Set("settings", "dark")
+
+	fmt.Printf("InvalidateTag(user:1) removed %d entries\n", cache.InvalidateTag("user:1"))
+	fmt.Printf("InvalidateTag(orders) removed %d entries\n", cache.InvalidateTag("orders")) // Already gone
+	fmt.Printf("DeletePrefix(session:) removed %d entries\n", cache.DeletePrefix("session:"))
+	fmt.Printf("Remaining keys: %v\n", cache.Keys())
+}
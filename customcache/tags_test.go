@@ -0,0 +1,64 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestInvalidateTagSparesRetaggedKeys races a retag with InvalidateTag. Whichever runs first, the
+// key must survive with its new tag: it was never tagged "old" while InvalidateTag ran.
+func TestInvalidateTagSparesRetaggedKeys(t *testing.T) {
+	cache := NewConcurrentCache()
+	defer cache.Close()
+
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("page:%d", i)
+		cache.SetWithTags(key, "v1", "old")
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cache.SetWithTags(key, "v2", "new")
+		}()
+		go func() {
+			defer wg.Done()
+			cache.InvalidateTag("old")
+		}()
+		wg.Wait()
+
+		if value, ok := cache.Get(key); !ok || value != "v2" {
+			t.Fatalf("iteration %d: Get(%s) = %v, %t; the retagged value was deleted", i, key, value, ok)
+		}
+	}
+}
+
+func TestDeletePrefixWriteThrough(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Store: store, WriteMode: WriteThrough})
+	defer cache.Close()
+
+	for _, key := range []string{"user:1", "user:2", "order:1"} {
+		cache.SetWithTags(key, key, "all")
+	}
+	if removed := cache.DeletePrefix("user:"); removed != 2 {
+		t.Fatalf("DeletePrefix(user:) removed %d, want 2", removed)
+	}
+	for _, key := range []string{"user:1", "user:2"} {
+		if _, err := store.Load(context.Background(), key); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("store still has %s: %v", key, err)
+		}
+	}
+	if removed := cache.InvalidateTag("all"); removed != 1 {
+		t.Fatalf("InvalidateTag(all) removed %d, want 1", removed)
+	}
+	if _, err := store.Load(context.Background(), "order:1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("store still has order:1: %v", err)
+	}
+}
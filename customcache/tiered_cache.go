@@ -0,0 +1,227 @@
+package customcache
+
+import (
+	"fmt"
+	"go-ex/pkg/cachestats"
+	"sync"
+	"time"
+)
+
+// A TieredCache puts a small in-process L1 in front of a bigger or shared L2, e.g. a remote cache
+// server. Reads are served from L1 when possible and fill L1 from L2 on an L1 miss; writes and
+// deletes go to L2 first and then to L1. Other processes sharing the L2 don't see our deletes in
+// their L1, so the L1 TTL bounds how long they can serve a stale value.
+//
+// Within one TieredCache, L1 never gets ahead of L2: a write that L2 rejects drops the key from L1
+// instead of caching the value, and a fill from L2 is skipped if the key was written or deleted
+// while L2 was being read, so a slow Get can't bring back a value that Delete just removed.
+
+// Cache is the minimal interface a cache level has to implement.
+// ConcurrentCache and ShardedCache implement it; cacheclient.Layer adapts the HTTP client.
+type Cache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+	Delete(key string)
+}
+
+// CheckedCache is a Cache that can report a write it rejected. A TieredCache whose L2 implements
+// it only caches values in L1 once L2 has accepted them.
+type CheckedCache interface {
+	Cache
+	SetE(key string, value interface{}) error
+}
+
+var (
+	_ Cache        = (*ConcurrentCache)(nil)
+	_ Cache        = (*ShardedCache)(nil)
+	_ CheckedCache = (*ConcurrentCache)(nil)
+	_ CheckedCache = (*TieredCache)(nil)
+)
+
+// TieredCacheConfig holds the L1 settings of a TieredCache
+type TieredCacheConfig struct {
+	L1MaxBytes int64         // Size of L1 (default 16 MB)
+	L1TTL      time.Duration // How long values stay in L1 (default 30s)
+	L1Sizer    Sizer         // How L1 entries are sized (default DefaultSizer)
+}
+
+// TieredStats reports how each level is doing
+type TieredStats struct {
+	L1       cachestats.Stats `json:"l1"`        // Hit ratio over all lookups
+	L2       cachestats.Stats `json:"l2"`        // Hit ratio over the lookups L1 missed
+	HitRatio float64          `json:"hit_ratio"` // Lookups served by either level
+}
+
+func (s TieredStats) String() string {
+	return fmt.Sprintf("l1_hit_ratio=%.2f l2_hit_ratio=%.2f hit_ratio=%.2f l1_size=%d l1_bytes=%d",
+		s.L1.HitRatio, s.L2.HitRatio, s.HitRatio, s.L1.Size, s.L1.Bytes)
+}
+
+// TieredCache is an L1 ConcurrentCache in front of any L2 Cache
+type TieredCache struct {
+	l1     *ConcurrentCache
+	l2     Cache
+	config TieredCacheConfig
+
+	l1Stats cachestats.Counters
+	l2Stats cachestats.Counters
+
+	stripes [tieredStripes]tieredStripe
+}
+
+const tieredStripes = 64
+
+// tieredStripe orders the L1 updates of the keys hashing to it. gen counts the writes and deletes,
+// so a Get can tell whether the value it read from L2 is still current.
+type tieredStripe struct {
+	mu  sync.Mutex
+	gen uint64
+}
+
+func (c *TieredCache) stripe(key string) *tieredStripe {
+	return &c.stripes[fnv64a(key)%tieredStripes]
+}
+
+// NewTieredCache creates a TieredCache with a fresh L1 in front of l2
+func NewTieredCache(l2 Cache, config TieredCacheConfig) *TieredCache {
+	if config.L1MaxBytes <= 0 {
+		config.L1MaxBytes = 16 << 20
+	}
+	if config.L1TTL <= 0 {
+		config.L1TTL = time.Second * 30
+	}
+	l1 := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		DefaultTTL:      config.L1TTL,
+		CleanupInterval: config.L1TTL,
+		MaxBytes:        config.L1MaxBytes,
+		Sizer:           config.L1Sizer,
+		Quiet:           true,
+	})
+	return &TieredCache{l1: l1, l2: l2, config: config}
+}
+
+// Get returns the value from L1, or from L2 (copying it into L1)
+func (c *TieredCache) Get(key string) (interface{}, bool) {
+	if value, found := c.l1.Get(key); found {
+		c.l1Stats.Hit()
+		return value, true
+	}
+	c.l1Stats.Miss()
+
+	s := c.stripe(key)
+	s.mu.Lock()
+	gen := s.gen
+	s.mu.Unlock()
+
+	value, found := c.l2.Get(key)
+	if !found {
+		c.l2Stats.Miss()
+		return nil, false
+	}
+	c.l2Stats.Hit()
+
+	// Only fill L1 if nobody wrote or deleted the key while L2 was being read
+	s.mu.Lock()
+	if s.gen == gen {
+		c.l1.Set(key, value)
+	}
+	s.mu.Unlock()
+	return value, true
+}
+
+// Set writes the value to L2 and then to L1. Use SetE to learn whether L2 accepted it.
+func (c *TieredCache) Set(key string, value interface{}) {
+	c.SetE(key, value)
+}
+
+// SetE writes the value to L2 and then to L1. If L2 is a CheckedCache and rejects the value,
+// the key is dropped from L1 rather than cached there, and the error is returned.
+func (c *TieredCache) SetE(key string, value interface{}) error {
+	var err error
+	if l2, ok := c.l2.(CheckedCache); ok {
+		err = l2.SetE(key, value)
+	} else {
+		c.l2.Set(key, value)
+	}
+
+	s := c.stripe(key)
+	s.mu.Lock()
+	s.gen++
+	if err != nil {
+		c.l1.Delete(key)
+	} else {
+		c.l1.Set(key, value)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	c.l1Stats.Set()
+	c.l2Stats.Set()
+	return nil
+}
+
+// Delete removes key from L2 and then from L1
+func (c *TieredCache) Delete(key string) {
+	c.l2.Delete(key)
+	s := c.stripe(key)
+	s.mu.Lock()
+	s.gen++
+	c.l1.Delete(key)
+	s.mu.Unlock()
+	c.l1Stats.Delete()
+	c.l2Stats.Delete()
+}
+
+// Stats returns the hit ratio of each level and of the cache as a whole
+func (c *TieredCache) Stats() TieredStats {
+	l1 := c.l1.Stats()
+	stats := TieredStats{L1: c.l1Stats.Snapshot(l1.Size), L2: c.l2Stats.Snapshot(0)}
+	stats.L1.Bytes, stats.L1.Evictions, stats.L1.Expirations = l1.Bytes, l1.Evictions, l1.Expirations
+	if lookups := stats.L1.Hits + stats.L1.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.L1.Hits+stats.L2.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// Close stops the L1's background work. The L2 is owned by the caller.
+func (c *TieredCache) Close() error {
+	return c.l1.Close()
+}
+
+// slowCache simulates a remote L2 by adding a round trip to every call
+type slowCache struct {
+	Cache
+	latency time.Duration
+}
+
+func (s slowCache) Get(key string) (interface{}, bool) {
+	time.Sleep(s.latency)
+	return s.Cache.Get(key)
+}
+
+func RunTieredCache() {
+	l2 := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Quiet: true})
+	defer l2.Close()
+	for i := 0; i < 100; i++ {
+		l2.Set(fmt.Sprintf("product:%d", i), fmt.Sprintf("Product #%d", i)) // Filled by other processes
+	}
+
+	cache := NewTieredCache(slowCache{Cache: l2, latency: time.Millisecond * 2}, TieredCacheConfig{L1MaxBytes: 1024})
+	defer cache.Close()
+
+	start := time.Now()
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 10; i++ { // A small hot set that fits in L1
+			cache.Get(fmt.Sprintf("product:%d", i))
+		}
+		cache.Get(fmt.Sprintf("product:%d", 50+round)) // Cold keys only L2 has
+		cache.Get("product:missing")
+	}
+	fmt.Printf("60 reads took %v\n", time.Since(start).Round(time.Millisecond))
+
+	cache.Set("product:1", "Product #1 (renamed)")
+	value, _ := l2.Get("product:1")
+	fmt.Printf("L2 after Set through the tiered cache: %v\n", value)
+	fmt.Printf("Stats: %s\n", cache.Stats())
+}
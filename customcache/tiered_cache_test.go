@@ -0,0 +1,126 @@
+package customcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// rejectingL2 is a ConcurrentCache L2 that refuses the values of one key
+type rejectingL2 struct {
+	*ConcurrentCache
+	reject string
+}
+
+func (l rejectingL2) SetE(key string, value interface{}) error {
+	if key == l.reject {
+		return errors.New("rejected")
+	}
+	return l.ConcurrentCache.SetE(key, value)
+}
+
+func (l rejectingL2) Set(key string, value interface{}) { l.SetE(key, value) }
+
+// blockingL2 holds every Get until release is closed, after telling reading
+type blockingL2 struct {
+	*ConcurrentCache
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (l blockingL2) Get(key string) (interface{}, bool) {
+	value, found := l.ConcurrentCache.Get(key)
+	l.reading <- struct{}{}
+	<-l.release
+	return value, found
+}
+
+func TestTieredCacheSkipsL1WhenL2Rejects(t *testing.T) {
+	l2 := NewConcurrentCache()
+	defer l2.Close()
+	cache := NewTieredCache(rejectingL2{l2, "bad"}, TieredCacheConfig{})
+	defer cache.Close()
+
+	if err := cache.SetE("good", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.l1.Peek("good"); !ok {
+		t.Fatal("an accepted value was not cached in L1")
+	}
+
+	cache.l1.Set("bad", "old") // A copy L1 picked up earlier
+	if err := cache.SetE("bad", "v"); err == nil {
+		t.Fatal("SetE reported no error for a value L2 rejected")
+	}
+	cache.Set("bad", "v")
+	if item, ok := cache.l1.Peek("bad"); ok {
+		t.Fatalf("L1 holds %v for a key whose write L2 rejected", item.Value)
+	}
+	if _, ok := cache.Get("bad"); ok {
+		t.Fatal("Get served a value L2 never accepted")
+	}
+	if stats := cache.Stats(); stats.L1.Sets != 1 || stats.L2.Sets != 1 {
+		t.Fatalf("stats %s counted rejected writes", stats)
+	}
+}
+
+func TestTieredCacheFillDoesNotResurrectDeletes(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		write func(c *TieredCache)
+		want  interface{} // What L1 may hold afterwards, nil for nothing
+	}{
+		{"delete", func(c *TieredCache) { c.Delete("k") }, nil},
+		{"set", func(c *TieredCache) { c.Set("k", "new") }, "new"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			l2 := blockingL2{NewConcurrentCache(), make(chan struct{}), make(chan struct{})}
+			defer l2.Close()
+			l2.ConcurrentCache.Set("k", "old")
+			cache := NewTieredCache(l2, TieredCacheConfig{L1TTL: time.Minute})
+			defer cache.Close()
+
+			got := make(chan interface{})
+			go func() {
+				value, _ := cache.Get("k")
+				got <- value
+			}()
+			<-l2.reading // The Get has read "old" from L2 and is about to fill L1
+			tt.write(cache)
+			close(l2.release)
+
+			if value := <-got; value != "old" {
+				t.Fatalf("Get = %v, want the value it read before the %s", value, tt.name)
+			}
+			item, ok := cache.l1.Peek("k")
+			if (tt.want == nil && ok) || (tt.want != nil && (!ok || item.Value != tt.want)) {
+				t.Fatalf("L1 holds %v (%t) after a %s raced the fill, want %v", item.Value, ok, tt.name, tt.want)
+			}
+		})
+	}
+}
+
+func TestTieredCacheFillsL1(t *testing.T) {
+	l2 := NewConcurrentCache()
+	defer l2.Close()
+	l2.Set("k", "v")
+	cache := NewTieredCache(l2, TieredCacheConfig{})
+	defer cache.Close()
+
+	for i := 0; i < 3; i++ {
+		if value, ok := cache.Get("k"); !ok || value != "v" {
+			t.Fatalf("Get(k) = %v, %t", value, ok)
+		}
+	}
+	if stats := cache.Stats(); stats.L1.Hits != 2 || stats.L1.Misses != 1 || stats.L2.Hits != 1 {
+		t.Fatalf("stats %s, want one L2 read and two L1 hits", stats)
+	}
+
+	cache.Delete("k")
+	if _, ok := cache.Get("k"); ok {
+		t.Fatal("Get(k) found it after Delete")
+	}
+	if _, ok := l2.Get("k"); ok {
+		t.Fatal("Delete left the key in L2")
+	}
+}
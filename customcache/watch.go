@@ -0,0 +1,192 @@
+package customcache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Watch lets components react when particular keys change. Every watcher gets its own buffered
+// channel; writers never block on a slow watcher, the watcher's OverflowPolicy decides instead.
+
+// EventType is the kind of change a watcher is told about
+type EventType int
+
+const (
+	EventSet    EventType = iota + 1 // Key added or updated
+	EventDelete                      // Key removed with Delete
+	EventExpire                      // Key removed because its TTL passed
+	EventEvict                       // Key removed to make room for other entries
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a single change to a key
+type Event struct {
+	Type     EventType
+	Key      string
+	OldValue interface{} // nil if the key did not exist before
+	NewValue interface{} // nil unless Type is EventSet
+	Time     time.Time
+	Missed   uint64 // Events dropped for this watcher since the previous delivered event
+}
+
+// OverflowPolicy decides what happens when a watcher's buffer is full
+type OverflowPolicy int
+
+const (
+	DropNewest   OverflowPolicy = iota // Discard the event that doesn't fit
+	DropOldest                         // Discard the oldest buffered event to make room
+	CloseWatcher                       // Close the watcher's channel; it has to Watch again
+)
+
+// WatchOptions configures a single watcher
+type WatchOptions struct {
+	Buffer   int // Channel buffer size (default 64)
+	Overflow OverflowPolicy
+}
+
+// watcher is a registered Watch call
+type watcher struct {
+	key    string
+	prefix bool // key is a prefix ("user:*" watches every key starting with "user:")
+	ch     chan Event
+	done   chan struct{} // Closed with ch, so the goroutine waiting on ctx exits too
+	opts   WatchOptions
+	missed uint64
+	closed bool
+}
+
+func (w *watcher) matches(key string) bool {
+	if w.prefix {
+		return strings.HasPrefix(key, w.key)
+	}
+	return key == w.key
+}
+
+// Watch returns a channel of changes to keyOrPrefix. A trailing "*" watches every key with that
+// prefix; anything else watches a single key. The channel is closed when ctx is done or the cache
+// is closed. Buffer size and overflow policy come from WatchBuffer and WatchOverflow in the config.
+func (c *ConcurrentCache) Watch(ctx context.Context, keyOrPrefix string) <-chan Event {
+	return c.WatchWithOptions(ctx, keyOrPrefix, WatchOptions{Buffer: c.config.WatchBuffer, Overflow: c.config.WatchOverflow})
+}
+
+// WatchWithOptions is Watch with a per-watcher buffer size and overflow policy
+func (c *ConcurrentCache) WatchWithOptions(ctx context.Context, keyOrPrefix string, opts WatchOptions) <-chan Event {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+	w := &watcher{key: keyOrPrefix, ch: make(chan Event, opts.Buffer), done: make(chan struct{}), opts: opts}
+	if strings.HasSuffix(keyOrPrefix, "*") {
+		w.key, w.prefix = strings.TrimSuffix(keyOrPrefix, "*"), true
+	}
+
+	c.mu.Lock()
+	c.watchers[w] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.stop:
+		case <-w.done:
+			return // Already closed by the CloseWatcher policy
+		}
+		c.mu.Lock()
+		c.closeWatcher(w)
+		c.mu.Unlock()
+	}()
+	return w.ch
+}
+
+// closeWatcher unregisters w and closes its channel. Called with c.mu held.
+func (c *ConcurrentCache) closeWatcher(w *watcher) {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	delete(c.watchers, w)
+	close(w.ch)
+	close(w.done)
+}
+
+// notify tells matching watchers about a change without ever blocking. Called with c.mu held,
+// which keeps events in the same order as the writes and stops sends racing with closeWatcher.
+func (c *ConcurrentCache) notify(typ EventType, key string, oldValue, newValue interface{}) {
+	if len(c.watchers) == 0 {
+		return
+	}
+	event := Event{Type: typ, Key: key, OldValue: oldValue, NewValue: newValue, Time: time.Now()}
+	for w := range c.watchers {
+		if !w.matches(key) {
+			continue
+		}
+		event.Missed = w.missed
+		select {
+		case w.ch <- event:
+			w.missed = 0
+			continue
+		default:
+		}
+
+		switch w.opts.Overflow {
+		case DropOldest:
+			select {
+			case <-w.ch: // Make room by dropping the oldest buffered event
+			default:
+			}
+			event.Missed = w.missed + 1
+			select {
+			case w.ch <- event:
+				w.missed = 0
+			default:
+				w.missed++ // The reader filled the slot again in the meantime
+			}
+		case CloseWatcher:
+			c.closeWatcher(w)
+		default: // DropNewest
+			w.missed++
+		}
+	}
+}
+
+func RunWatch() {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{CleanupInterval: time.Millisecond * 50})
+	defer cache.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := cache.Watch(ctx, "user:*")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			fmt.Printf("Event: %-6s key=%s old=%v new=%v\n", event.Type, event.Key, event.OldValue, event.NewValue)
+		}
+		fmt.Println("Watch channel closed.")
+	}()
+
+	cache.Set("user:1", "Alice")
+	cache.Set("user:1", "Alicia")
+	cache.Set("order:1", "ignored") // Not under the watched prefix
+	cache.SetWithTTL("user:2", "Bob", time.Millisecond*20)
+	cache.Delete("user:1")
+
+	time.Sleep(time.Millisecond * 150) // Let user:2 expire and the sweeper report it
+	cancel()
+	<-done
+}
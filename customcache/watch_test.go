@@ -0,0 +1,166 @@
+package customcache
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// nextEvent waits for the next event on ch
+func nextEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case event, ok := <-ch:
+		if !ok {
+			t.Fatal("watch channel closed")
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event")
+	}
+	return Event{}
+}
+
+// waitClosed drains ch until it is closed and returns what was left in it
+func waitClosed(t *testing.T, ch <-chan Event) []Event {
+	t.Helper()
+	var events []Event
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		case <-deadline:
+			t.Fatal("watch channel was never closed")
+		}
+	}
+}
+
+func TestWatchKeyAndPrefix(t *testing.T) {
+	cache := NewConcurrentCache()
+	defer cache.Close()
+	keyEvents := cache.Watch(context.Background(), "user:1")
+	prefixEvents := cache.Watch(context.Background(), "user:*")
+
+	cache.Set("user:1", "Alice")
+	cache.Set("user:1", "Alicia")
+	cache.Set("order:1", "ignored")
+	cache.Delete("user:1")
+	cache.Set("user:2", "Bob")
+
+	want := []Event{
+		{Type: EventSet, Key: "user:1", NewValue: "Alice"},
+		{Type: EventSet, Key: "user:1", OldValue: "Alice", NewValue: "Alicia"},
+		{Type: EventDelete, Key: "user:1", OldValue: "Alicia"},
+	}
+	for _, w := range want {
+		if got := nextEvent(t, keyEvents); got.Type != w.Type || got.Key != w.Key || got.OldValue != w.OldValue || got.NewValue != w.NewValue {
+			t.Fatalf("key watcher got %+v, want %+v", got, w)
+		}
+		if got := nextEvent(t, prefixEvents); got.Type != w.Type || got.Key != w.Key {
+			t.Fatalf("prefix watcher got %s %s, want %s %s", got.Type, got.Key, w.Type, w.Key)
+		}
+	}
+	if got := nextEvent(t, prefixEvents); got.Type != EventSet || got.Key != "user:2" {
+		t.Fatalf("prefix watcher got %s %s, want set user:2", got.Type, got.Key)
+	}
+}
+
+func TestWatchEvictAndExpireEvents(t *testing.T) {
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{MaxEntries: 1, CleanupInterval: 5 * time.Millisecond})
+	defer cache.Close()
+	events := cache.Watch(context.Background(), "*")
+
+	cache.Set("a", 1)
+	cache.Set("b", 2) // Evicts a
+	for _, want := range []struct {
+		typ EventType
+		key string
+	}{{EventSet, "a"}, {EventEvict, "a"}, {EventSet, "b"}} {
+		if got := nextEvent(t, events); got.Type != want.typ || got.Key != want.key {
+			t.Fatalf("got %s %s, want %s %s", got.Type, got.Key, want.typ, want.key)
+		}
+	}
+
+	cache.SetWithTTL("b", 3, time.Millisecond)
+	nextEvent(t, events)
+	if got := nextEvent(t, events); got.Type != EventExpire || got.Key != "b" || got.OldValue != 3 {
+		t.Fatalf("got %+v, want b expiring", got)
+	}
+}
+
+func TestWatchClosesWithContextAndCache(t *testing.T) {
+	cache := NewConcurrentCache()
+	ctx, cancel := context.WithCancel(context.Background())
+	byContext := cache.Watch(ctx, "k")
+	byClose := cache.Watch(context.Background(), "k")
+
+	cancel()
+	waitClosed(t, byContext)
+	cache.Set("k", 1)
+	if got := nextEvent(t, byClose); got.Type != EventSet {
+		t.Fatalf("got %+v", got)
+	}
+	cache.Close()
+	waitClosed(t, byClose)
+}
+
+func TestWatchOverflowPolicies(t *testing.T) {
+	cache := NewConcurrentCache()
+	defer cache.Close()
+	newest := cache.WatchWithOptions(context.Background(), "k", WatchOptions{Buffer: 2, Overflow: DropNewest})
+	oldest := cache.WatchWithOptions(context.Background(), "k", WatchOptions{Buffer: 2, Overflow: DropOldest})
+
+	for i := 1; i <= 5; i++ {
+		cache.Set("k", i)
+	}
+
+	// DropOldest keeps the latest events
+	a, b := nextEvent(t, oldest), nextEvent(t, oldest)
+	if a.NewValue != 4 || b.NewValue != 5 || a.Missed == 0 {
+		t.Fatalf("DropOldest kept %v and %v (Missed %d), want 4 and 5 with drops reported", a.NewValue, b.NewValue, a.Missed)
+	}
+
+	// DropNewest keeps the first events and reports the rest on the next one it delivers
+	if a, b := nextEvent(t, newest), nextEvent(t, newest); a.NewValue != 1 || b.NewValue != 2 {
+		t.Fatalf("DropNewest kept %v and %v, want 1 and 2", a.NewValue, b.NewValue)
+	}
+	cache.Set("k", 6)
+	if got := nextEvent(t, newest); got.NewValue != 6 || got.Missed != 3 {
+		t.Fatalf("DropNewest delivered %v with Missed %d, want 6 with 3", got.NewValue, got.Missed)
+	}
+}
+
+func TestWatchCloseWatcherPolicy(t *testing.T) {
+	cache := NewConcurrentCache()
+	defer cache.Close()
+	baseline := runtime.NumGoroutine()
+	events := cache.WatchWithOptions(context.Background(), "k", WatchOptions{Buffer: 2, Overflow: CloseWatcher})
+
+	for i := 1; i <= 3; i++ {
+		cache.Set("k", i)
+	}
+	if left := waitClosed(t, events); len(left) != 2 || left[0].NewValue != 1 || left[1].NewValue != 2 {
+		t.Fatalf("closed watcher delivered %v, want the two buffered events", left)
+	}
+	cache.mu.RLock()
+	watchers := len(cache.watchers)
+	cache.mu.RUnlock()
+	if watchers != 0 {
+		t.Fatalf("%d watchers still registered", watchers)
+	}
+
+	// The goroutine waiting on the watcher's context must exit, although ctx is never done
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines, %d before Watch: the closed watcher's goroutine leaked", runtime.NumGoroutine(), baseline)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cache.Set("k", 4) // No longer watched, and must not panic on the closed channel
+}
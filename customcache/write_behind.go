@@ -0,0 +1,298 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Write-behind acknowledges writes as soon as the cache is updated and persists them later.
+// Repeated writes to the same key before a flush are coalesced, so only the latest one reaches the store.
+
+// pendingWrite is the latest unflushed write for a key
+type pendingWrite struct {
+	value   interface{}
+	deleted bool
+}
+
+// writeBehind holds the pending writes and runs the background flusher
+type writeBehind struct {
+	config ConcurrentCacheConfig
+
+	mu       sync.Mutex
+	pending  map[string]pendingWrite // Waiting for the next flush
+	inflight map[string]pendingWrite // Being written by the current flush
+	closed   bool                    // Set by close: no flusher is left, so writes go straight to the store
+
+	dropped atomic.Uint64 // Writes given up on after MaxRetries
+
+	flushMu   sync.Mutex    // Only one flush runs at a time so writes reach the store in order
+	kick      chan struct{} // Asks the flusher to flush before the next tick
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newWriteBehind(config ConcurrentCacheConfig) *writeBehind {
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	return &writeBehind{
+		config:   config,
+		pending:  make(map[string]pendingWrite),
+		inflight: make(map[string]pendingWrite),
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// enqueue records the latest write for key, replacing any unflushed one. After close the write is
+// persisted right away instead, since nothing would flush it.
+func (wb *writeBehind) enqueue(key string, value interface{}, deleted bool) {
+	wb.mu.Lock()
+	if wb.closed {
+		delete(wb.pending, key) // Superseded: the final flush must not write it after this one
+		wb.mu.Unlock()
+		wb.writeNow(key, pendingWrite{value: value, deleted: deleted})
+		return
+	}
+	wb.pending[key] = pendingWrite{value: value, deleted: deleted}
+	full := wb.config.FlushBatchSize > 0 && len(wb.pending) >= wb.config.FlushBatchSize
+	wb.mu.Unlock()
+
+	if full {
+		select {
+		case wb.kick <- struct{}{}:
+		default: // A flush is already requested
+		}
+	}
+}
+
+// latest returns the newest write for key that has not reached the store yet
+func (wb *writeBehind) latest(key string) (pendingWrite, bool) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if w, ok := wb.pending[key]; ok {
+		return w, true
+	}
+	w, ok := wb.inflight[key]
+	return w, ok
+}
+
+// flushRoutine periodically flushes pending writes until close is called
+func (wb *writeBehind) flushRoutine() {
+	defer close(wb.done)
+	ticker := time.NewTicker(wb.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-wb.kick:
+		case <-wb.stop:
+			return
+		}
+		wb.flush()
+	}
+}
+
+// flush writes every pending write to the store, retrying failed keys with exponential backoff.
+// Writes that still fail are dropped, counted in DroppedWrites and reported through OnStoreError
+// (or printed if it isn't set).
+func (wb *writeBehind) flush() error {
+	wb.flushMu.Lock()
+	defer wb.flushMu.Unlock()
+
+	wb.mu.Lock()
+	batch := wb.pending
+	wb.pending = make(map[string]pendingWrite)
+	wb.inflight = batch
+	wb.mu.Unlock()
+
+	var errs []error
+	for key, w := range batch {
+		if err := wb.persist(key, w); err != nil {
+			wb.drop(key, err)
+			errs = append(errs, fmt.Errorf("key %q: %w", key, err))
+		}
+	}
+
+	wb.mu.Lock()
+	wb.inflight = make(map[string]pendingWrite)
+	wb.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// writeNow persists a write made after close. flushMu orders it after the final flush.
+func (wb *writeBehind) writeNow(key string, w pendingWrite) {
+	wb.flushMu.Lock()
+	defer wb.flushMu.Unlock()
+	if err := wb.persist(key, w); err != nil {
+		wb.drop(key, err)
+	}
+}
+
+// drop counts a write given up on and reports it through OnStoreError (or prints it)
+func (wb *writeBehind) drop(key string, err error) {
+	wb.dropped.Add(1)
+	if wb.config.OnStoreError != nil {
+		wb.config.OnStoreError(key, err)
+	} else {
+		fmt.Printf("Cache: write-behind dropped key '%s' after %d retries: %v\n", key, wb.config.MaxRetries, err)
+	}
+}
+
+// persist writes a single key, retrying up to MaxRetries times
+func (wb *writeBehind) persist(key string, w pendingWrite) error {
+	backoff := wb.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		var err error
+		if w.deleted {
+			err = wb.config.Store.Delete(context.Background(), key)
+		} else {
+			err = wb.config.Store.Store(context.Background(), key, w.value)
+		}
+		if err == nil || attempt >= wb.config.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// close stops the flusher and flushes whatever is still pending. Later writes are persisted
+// as they are made.
+func (wb *writeBehind) close() error {
+	wb.closeOnce.Do(func() {
+		close(wb.stop)
+		<-wb.done
+		wb.mu.Lock()
+		wb.closed = true
+		wb.mu.Unlock()
+		wb.closeErr = wb.flush()
+	})
+	return wb.closeErr
+}
+
+// Flush writes all pending write-behind writes to the store now
+func (c *ConcurrentCache) Flush() error {
+	if c.behind == nil {
+		return nil
+	}
+	return c.behind.flush()
+}
+
+// DroppedWrites returns how many write-behind writes were given up on after MaxRetries
+func (c *ConcurrentCache) DroppedWrites() uint64 {
+	if c.behind == nil {
+		return 0
+	}
+	return c.behind.dropped.Load()
+}
+
+// unflushed returns the newest write-behind write for key that has not reached the store yet
+func (c *ConcurrentCache) unflushed(key string) (pendingWrite, bool) {
+	if c.behind == nil {
+		return pendingWrite{}, false
+	}
+	return c.behind.latest(key)
+}
+
+// readThrough loads a missing key from the store and caches it with DefaultTTL. A write-behind
+// write that hasn't been flushed yet is newer than the store's copy, so it wins.
+func (c *ConcurrentCache) readThrough(key string) (Item, bool) {
+	value, err := c.storeLoads.do(context.Background(), key, func() (interface{}, error) {
+		var value interface{}
+		if w, ok := c.unflushed(key); ok {
+			if w.deleted {
+				return nil, ErrNotFound // The store still has the old value, but it is already deleted
+			}
+			value = w.value
+		} else {
+			var err error
+			if value, err = c.config.Store.Load(context.Background(), key); err != nil {
+				return nil, err
+			}
+		}
+
+		c.mu.Lock()
+		defer c.unlockAndSync(key)
+		if current, ok := c.data[key]; ok && !current.expired(time.Now()) {
+			return current, nil // A concurrent Set wins over the stored copy
+		}
+		if w, ok := c.unflushed(key); ok { // Written while the store was being read
+			if w.deleted {
+				return nil, ErrNotFound
+			}
+			value = w.value
+		}
+		item := Item{Value: value, ExpiresAt: expiresAt(c.config.DefaultTTL), CAS: c.cas.Add(1)}
+		c.put(key, item)
+		return item, nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			c.storeFailed(key, err)
+		}
+		return Item{}, false
+	}
+	return value.(Item), true
+}
+
+// storeFailed reports a store error through OnStoreError, or prints it when no handler is set
+func (c *ConcurrentCache) storeFailed(key string, err error) {
+	if c.config.OnStoreError != nil {
+		c.config.OnStoreError(key, err)
+		return
+	}
+	fmt.Printf("Cache: store error for key '%s': %v\n", key, err)
+}
+
+func RunWriteBehind() {
+	dir, err := os.MkdirTemp("", "customcache-store-")
+	if err != nil {
+		fmt.Printf("Failed to create store directory: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		fmt.Printf("Failed to create file store: %v\n", err)
+		return
+	}
+
+	// Write-behind: Sets return immediately and are flushed in the background
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		Store:          store,
+		WriteMode:      WriteBehind,
+		FlushInterval:  time.Millisecond * 500,
+		FlushBatchSize: 100,
+		MaxRetries:     3,
+		RetryBackoff:   time.Millisecond * 50,
+	})
+	for i := 0; i < 5; i++ {
+		cache.Set("counter", i) // Coalesced: only the last value is written
+	}
+	cache.Set("user:1", "Alice")
+	cache.Delete("user:1")
+	if err := cache.Close(); err != nil { // Flushes the pending writes
+		fmt.Printf("Flush failed: %v\n", err)
+	}
+
+	// Write-through: a fresh cache reads the flushed values back from the store
+	reader := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Store: store, WriteMode: WriteThrough})
+	if value, found := reader.Get("counter"); found {
+		fmt.Printf("Read through from store: counter=%v\n", value)
+	}
+	reader.Get("user:1") // Deleted before the flush, so never persisted
+	reader.Set("user:2", "Bob")
+	value, err := store.Load(context.Background(), "user:2")
+	fmt.Printf("Persisted synchronously: user:2=%v (err=%v)\n", value, err)
+}
@@ -0,0 +1,170 @@
+package customcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestFileStore(t *testing.T) *FileStore {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestReadThroughAppliesDefaultTTL(t *testing.T) {
+	store := newTestFileStore(t)
+	if err := store.Store(context.Background(), "user:1", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Store: store, WriteMode: WriteThrough, DefaultTTL: time.Minute})
+	defer cache.Close()
+
+	if value, ok := cache.Get("user:1"); !ok || value != "Alice" {
+		t.Fatalf("Get(user:1) = %v, %t", value, ok)
+	}
+	if ttl, ok := cache.TTL("user:1"); !ok || ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("TTL(user:1) = %v, %t; read-through values must get DefaultTTL", ttl, ok)
+	}
+}
+
+func TestReadThroughPrefersUnflushedWrites(t *testing.T) {
+	store := newTestFileStore(t)
+	if err := store.Store(context.Background(), "user:1", "old"); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		Store:         store,
+		WriteMode:     WriteBehind,
+		FlushInterval: time.Hour, // Nothing is flushed during the test
+		MaxEntries:    1,
+	})
+	defer cache.Close()
+
+	cache.Set("user:1", "new")
+	cache.Set("user:2", "other") // Evicts user:1 while its write is still pending
+	if value, ok := cache.Get("user:1"); !ok || value != "new" {
+		t.Fatalf("Get(user:1) = %v, %t; the store's older copy shadowed the pending write", value, ok)
+	}
+
+	cache.Delete("user:1")
+	cache.Set("user:2", "other")
+	if value, ok := cache.Get("user:1"); ok {
+		t.Fatalf("Get(user:1) = %v after a pending delete", value)
+	}
+
+	if err := cache.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(context.Background(), "user:1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("store still has user:1 after the flush: %v", err)
+	}
+}
+
+// failingStore is a FileStore whose writes always fail
+type failingStore struct {
+	*FileStore
+}
+
+func (s failingStore) Store(ctx context.Context, key string, value interface{}) error {
+	return errors.New("disk full")
+}
+
+func TestWriteBehindReportsDroppedWrites(t *testing.T) {
+	var reported atomic.Int32
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		Store:         failingStore{newTestFileStore(t)},
+		WriteMode:     WriteBehind,
+		FlushInterval: time.Hour,
+		MaxRetries:    1,
+		OnStoreError:  func(key string, err error) { reported.Add(1) },
+	})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	if err := cache.Flush(); err == nil {
+		t.Fatal("Flush reported no error")
+	}
+	if dropped := cache.DroppedWrites(); dropped != 2 {
+		t.Fatalf("DroppedWrites() = %d, want 2", dropped)
+	}
+	if reported.Load() != 2 {
+		t.Fatalf("OnStoreError called %d times, want 2", reported.Load())
+	}
+}
+
+func TestWriteBehindPersistsWritesAfterClose(t *testing.T) {
+	store := newTestFileStore(t)
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Store: store, WriteMode: WriteBehind, FlushInterval: time.Hour, Quiet: true})
+	cache.Set("before", "1")
+	cache.Set("gone", "1")
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.Set("after", "2")
+	cache.Delete("gone")
+	if value, err := store.Load(context.Background(), "after"); err != nil || value != "2" {
+		t.Fatalf("store has after = %v, %v; a write after Close never reached it", value, err)
+	}
+	if _, err := store.Load(context.Background(), "gone"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("store still has gone after a Delete after Close: %v", err)
+	}
+	if value, err := store.Load(context.Background(), "before"); err != nil || value != "1" {
+		t.Fatalf("store has before = %v, %v", value, err)
+	}
+}
+
+// TestWriteBehindWritesRacingClose keeps writing while the cache closes: whether a write lands in
+// the final flush or after it, the store must end up with the cache's latest value of every key.
+func TestWriteBehindWritesRacingClose(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		store := newTestFileStore(t)
+		cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{Store: store, WriteMode: WriteBehind, FlushInterval: time.Hour, Quiet: true})
+
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					cache.Set(fmt.Sprintf("key:%d", i%5), fmt.Sprintf("%d-%d", w, i))
+				}
+			}(w)
+		}
+		cache.Close()
+		wg.Wait()
+
+		for i := 0; i < 5; i++ {
+			key := fmt.Sprintf("key:%d", i)
+			want, _ := cache.Get(key)
+			if got, err := store.Load(context.Background(), key); err != nil || got != want {
+				t.Fatalf("round %d: store has %s = %v, %v, cache has %v", round, key, got, err, want)
+			}
+		}
+	}
+}
+
+func TestWriteBehindReportsFailedWritesAfterClose(t *testing.T) {
+	var reported atomic.Int32
+	cache := NewConcurrentCacheWithConfig(ConcurrentCacheConfig{
+		Store:        failingStore{newTestFileStore(t)},
+		WriteMode:    WriteBehind,
+		OnStoreError: func(key string, err error) { reported.Add(1) },
+		Quiet:        true,
+	})
+	cache.Close()
+
+	cache.Set("a", 1)
+	if dropped := cache.DroppedWrites(); dropped != 1 || reported.Load() != 1 {
+		t.Fatalf("DroppedWrites() = %d, OnStoreError called %d times, want 1 and 1", dropped, reported.Load())
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"go-ex/customcache"
+	"go-ex/customcache/httpcache"
+	"go-ex/customcache/peercache"
 	"go-ex/hungrygophers"
 	"go-ex/pkg/sharedresource"
 	"go-ex/processing"
@@ -11,7 +13,7 @@ import (
 
 func main() {
 	// Hardcoded variable to choose the program to run
-	// Options: "communicate", "process", "sharedresource", "sharedresourcemap", "simplecache", "concurrentcache", "ratelimiter", "taskprocessor"
+	// Options: "communicate", "process", "sharedresource", "sharedresourcemap", "simplecache", "concurrentcache", "expiringcache", "getorload", "stalewhilerevalidate", "bloomguard", "writebehind", "snapshot", "aof", "watch", "taginvalidation", "atomicops", "bytebudget", "shardedcache", "tieredcache", "namespaces", "slabcache", "peercache", "httpcache", "ratelimiter", "taskprocessor"
 	programToRun := "gophersemaphore" // You can change this to "process" to test the other part
 
 	switch programToRun {
@@ -30,6 +32,57 @@ func main() {
 	case "concurrentcache":
 		fmt.Println("Running Concurrent Cache Program...")
 		customcache.RunConcurrentCache()
+	case "expiringcache":
+		fmt.Println("Running Expiring Cache Program...")
+		customcache.RunExpiringCache()
+	case "getorload":
+		fmt.Println("Running Get Or Load Cache Program...")
+		customcache.RunGetOrLoad()
+	case "stalewhilerevalidate":
+		fmt.Println("Running Stale While Revalidate Program...")
+		customcache.RunStaleWhileRevalidate()
+	case "bloomguard":
+		fmt.Println("Running Bloom Filter Guard Program...")
+		customcache.RunBloomGuard()
+	case "writebehind":
+		fmt.Println("Running Write Behind Cache Program...")
+		customcache.RunWriteBehind()
+	case "snapshot":
+		fmt.Println("Running Cache Snapshot Program...")
+		customcache.RunSnapshot()
+	case "aof":
+		fmt.Println("Running Append-Only Log Cache Program...")
+		customcache.RunAOF()
+	case "watch":
+		fmt.Println("Running Cache Watch Program...")
+		customcache.RunWatch()
+	case "taginvalidation":
+		fmt.Println("Running Tag Invalidation Program...")
+		customcache.RunTagInvalidation()
+	case "atomicops":
+		fmt.Println("Running Atomic Cache Operations Program...")
+		customcache.RunAtomicOps()
+	case "bytebudget":
+		fmt.Println("Running Byte Budget Cache Program...")
+		customcache.RunByteBudget()
+	case "shardedcache":
+		fmt.Println("Running Sharded Cache Program...")
+		customcache.RunShardedCache()
+	case "slabcache":
+		fmt.Println("Running Slab Cache Program...")
+		customcache.RunSlabCache()
+	case "tieredcache":
+		fmt.Println("Running Tiered Cache Program...")
+		customcache.RunTieredCache()
+	case "namespaces":
+		fmt.Println("Running Namespaced Cache Program...")
+		customcache.RunNamespaces()
+	case "httpcache":
+		fmt.Println("Running HTTP Response Cache Program...")
+		httpcache.RunHTTPCache()
+	case "peercache":
+		fmt.Println("Running Peer Cache Program...")
+		peercache.RunPeerCache()
 	case "ratelimiter":
 		fmt.Println("Running Rate Limiter Program...")
 		ratelimiter.RunRateLimiter()
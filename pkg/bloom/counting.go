@@ -0,0 +1,209 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// A Bloom filter answers "definitely not present" or "maybe present" using a few bits per item.
+// The counting variant keeps a small counter per slot instead of a bit, so items can be removed again.
+// Counters are 8 bits and saturate at 255; a saturated counter is never decremented, which keeps
+// removals from ever causing false negatives.
+
+// ErrIncompatible is returned when merging filters with different sizes or hash counts
+var ErrIncompatible = errors.New("bloom: filters have different parameters")
+
+const (
+	filterMagic = "CBF1"
+	maxCount    = math.MaxUint8
+)
+
+// CountingFilter is a counting Bloom filter, safe for concurrent use
+type CountingFilter struct {
+	mu       sync.RWMutex
+	counters []uint8
+	k        uint32 // Number of hash functions
+	n        uint64 // Items added minus items removed
+}
+
+// New creates a filter with m counters and k hash functions
+func New(m uint64, k uint32) *CountingFilter {
+	return &CountingFilter{counters: make([]uint8, max(m, 1)), k: max(k, 1)}
+}
+
+// NewWithEstimates creates a filter sized to hold n items with a false-positive rate of about fpr
+func NewWithEstimates(n uint64, fpr float64) *CountingFilter {
+	m, k := EstimateParameters(n, fpr)
+	return New(m, k)
+}
+
+// EstimateParameters returns the number of counters m and hash functions k that keep the
+// false-positive rate at fpr for n items: m = -n*ln(fpr)/ln(2)^2 and k = m/n*ln(2)
+func EstimateParameters(n uint64, fpr float64) (m uint64, k uint32) {
+	n = max(n, 1)
+	if fpr <= 0 || fpr >= 1 {
+		fpr = 0.01
+	}
+	m = uint64(math.Ceil(-float64(n) * math.Log(fpr) / (math.Ln2 * math.Ln2)))
+	k = uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return m, k
+}
+
+// hashes returns the two base hashes of key used for double hashing
+func hashes(key string) (uint64, uint64) {
+	// FNV-1a, so the positions are stable across processes and serialized filters stay valid
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	// splitmix64 finalizer for the second hash
+	h2 := h + 0x9E3779B97F4A7C15
+	h2 = (h2 ^ (h2 >> 30)) * 0xBF58476D1CE4E5B9
+	h2 = (h2 ^ (h2 >> 27)) * 0x94D049BB133111EB
+	h2 ^= h2 >> 31
+	return h, h2 | 1 // Odd, so the probes don't repeat early
+}
+
+// positions calls fn with each of the k counter positions of key
+func (f *CountingFilter) positions(key string, fn func(i uint64)) {
+	h1, h2 := hashes(key)
+	m := uint64(len(f.counters))
+	for i := uint64(0); i < uint64(f.k); i++ {
+		fn((h1 + i*h2) % m)
+	}
+}
+
+// Add inserts key into the filter
+func (f *CountingFilter) Add(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.positions(key, func(i uint64) {
+		if f.counters[i] < maxCount {
+			f.counters[i]++
+		}
+	})
+	f.n++
+}
+
+// Remove takes key out of the filter and reports whether it may have been present.
+// Only remove keys that were added; removing others can cause false negatives.
+func (f *CountingFilter) Remove(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.test(key) {
+		return false
+	}
+	f.positions(key, func(i uint64) {
+		if f.counters[i] < maxCount { // A saturated counter no longer knows its real count
+			f.counters[i]--
+		}
+	})
+	if f.n > 0 {
+		f.n--
+	}
+	return true
+}
+
+// Test reports whether key may be in the filter. False means it definitely is not.
+func (f *CountingFilter) Test(key string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.test(key)
+}
+
+func (f *CountingFilter) test(key string) bool {
+	present := true
+	f.positions(key, func(i uint64) {
+		if f.counters[i] == 0 {
+			present = false
+		}
+	})
+	return present
+}
+
+// Merge adds the items of other to f. Both filters must have the same m and k.
+func (f *CountingFilter) Merge(other *CountingFilter) error {
+	if f == other {
+		return errors.New("bloom: cannot merge a filter with itself")
+	}
+	// Copy other first and never hold both locks, so a.Merge(b) and b.Merge(a) can't deadlock
+	other.mu.RLock()
+	counters := append([]uint8(nil), other.counters...)
+	k, n := other.k, other.n
+	other.mu.RUnlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.counters) != len(counters) || f.k != k {
+		return fmt.Errorf("%w: m=%d k=%d vs m=%d k=%d", ErrIncompatible, len(f.counters), f.k, len(counters), k)
+	}
+	for i, count := range counters {
+		f.counters[i] = uint8(min(int(f.counters[i])+int(count), maxCount))
+	}
+	f.n += n
+	return nil
+}
+
+// Len returns the number of items added minus the number removed
+func (f *CountingFilter) Len() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.n
+}
+
+// Params returns the number of counters and hash functions
+func (f *CountingFilter) Params() (m uint64, k uint32) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return uint64(len(f.counters)), f.k
+}
+
+// FalsePositiveRate estimates the current false-positive rate from how many counters are in use:
+// a missing key is a false positive when all k of its counters happen to be non-zero.
+func (f *CountingFilter) FalsePositiveRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	used := 0
+	for _, count := range f.counters {
+		if count > 0 {
+			used++
+		}
+	}
+	return math.Pow(float64(used)/float64(len(f.counters)), float64(f.k))
+}
+
+// MarshalBinary encodes the filter as magic, m, k, n and the counters
+func (f *CountingFilter) MarshalBinary() ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	data := make([]byte, 0, len(filterMagic)+8+4+8+len(f.counters))
+	data = append(data, filterMagic...)
+	data = binary.BigEndian.AppendUint64(data, uint64(len(f.counters)))
+	data = binary.BigEndian.AppendUint32(data, f.k)
+	data = binary.BigEndian.AppendUint64(data, f.n)
+	return append(data, f.counters...), nil
+}
+
+// UnmarshalBinary replaces the filter with one encoded by MarshalBinary
+func (f *CountingFilter) UnmarshalBinary(data []byte) error {
+	const header = len(filterMagic) + 8 + 4 + 8
+	if len(data) < header || string(data[:len(filterMagic)]) != filterMagic {
+		return errors.New("bloom: not a serialized counting filter")
+	}
+	m := binary.BigEndian.Uint64(data[4:12])
+	k := binary.BigEndian.Uint32(data[12:16])
+	n := binary.BigEndian.Uint64(data[16:24])
+	if m == 0 || k == 0 || uint64(len(data)-header) != m {
+		return fmt.Errorf("bloom: corrupt filter (m=%d k=%d, %d counter bytes)", m, k, len(data)-header)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counters = append([]uint8(nil), data[header:]...)
+	f.k, f.n = k, n
+	return nil
+}
@@ -0,0 +1,190 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddTestRemove(t *testing.T) {
+	f := NewWithEstimates(1000, 0.001)
+	for i := 0; i < 100; i++ {
+		f.Add(fmt.Sprintf("key:%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		if key := fmt.Sprintf("key:%d", i); !f.Test(key) {
+			t.Fatalf("Test(%s) = false for an added key", key)
+		}
+	}
+	if f.Len() != 100 {
+		t.Fatalf("Len() = %d, want 100", f.Len())
+	}
+	if f.Remove("never added") {
+		t.Fatal("Remove reported a key that was never added")
+	}
+
+	for i := 0; i < 100; i += 2 {
+		if key := fmt.Sprintf("key:%d", i); !f.Remove(key) {
+			t.Fatalf("Remove(%s) = false for an added key", key)
+		}
+	}
+	for i := 1; i < 100; i += 2 {
+		if key := fmt.Sprintf("key:%d", i); !f.Test(key) {
+			t.Fatalf("removing other keys made %s a false negative", key)
+		}
+	}
+	for i := 1; i < 100; i += 2 {
+		f.Remove(fmt.Sprintf("key:%d", i))
+	}
+	for i, count := range f.counters {
+		if count != 0 {
+			t.Fatalf("counter %d = %d after every key was removed", i, count)
+		}
+	}
+	if f.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", f.Len())
+	}
+}
+
+func TestCounterSaturation(t *testing.T) {
+	f := New(64, 3)
+	for i := 0; i < 300; i++ {
+		f.Add("hot")
+	}
+	f.positions("hot", func(i uint64) {
+		if f.counters[i] != maxCount {
+			t.Fatalf("counter %d = %d after 300 adds, want it stuck at %d", i, f.counters[i], maxCount)
+		}
+	})
+
+	// A saturated counter has lost its real count, so it is never decremented
+	for i := 0; i < 300; i++ {
+		f.Remove("hot")
+	}
+	if !f.Test("hot") {
+		t.Fatal("removing a saturated key made it a false negative")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(1024, 4), New(1024, 4)
+	a.Add("a")
+	b.Add("b")
+	b.Add("b")
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if !a.Test("a") || !a.Test("b") || a.Len() != 3 {
+		t.Fatalf("merged filter: a=%t b=%t len=%d", a.Test("a"), a.Test("b"), a.Len())
+	}
+	a.Remove("b")
+	if !a.Test("b") {
+		t.Fatal("merged counts were not added: one Remove undid two Adds")
+	}
+
+	for _, other := range []*CountingFilter{New(512, 4), New(1024, 3)} {
+		if err := a.Merge(other); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("Merge with m, k = %v: %v, want ErrIncompatible", fmt.Sprint(other.Params()), err)
+		}
+	}
+	if err := a.Merge(a); err == nil {
+		t.Fatal("merging a filter with itself succeeded")
+	}
+	if a.Len() != 2 {
+		t.Fatalf("failed merges changed Len() to %d", a.Len())
+	}
+}
+
+func TestMergeBothWaysDoesNotDeadlock(t *testing.T) {
+	a, b := New(1024, 4), New(1024, 4)
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					a.Merge(b)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					b.Merge(a)
+				}
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("a.Merge(b) and b.Merge(a) deadlocked")
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	f := New(2048, 5)
+	for i := 0; i < 200; i++ {
+		f.Add(fmt.Sprintf("key:%d", i))
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored CountingFilter
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if m, k := restored.Params(); m != 2048 || k != 5 || restored.Len() != 200 {
+		t.Fatalf("restored m=%d k=%d len=%d", m, k, restored.Len())
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		if restored.Test(key) != f.Test(key) {
+			t.Fatalf("Test(%s) differs after the round trip", key)
+		}
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":       nil,
+		"wrong magic": append([]byte("XXXX"), data[4:]...),
+		"truncated":   data[:len(data)-1],
+		"zero k":      append(append(append([]byte(nil), data[:12]...), 0, 0, 0, 0), data[16:]...),
+	} {
+		if err := restored.UnmarshalBinary(bad); err == nil {
+			t.Fatalf("UnmarshalBinary accepted %s data", name)
+		}
+	}
+	if restored.Len() != 200 {
+		t.Fatal("a rejected UnmarshalBinary changed the filter")
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	const n, target = 10_000, 0.01
+	f := NewWithEstimates(n, target)
+	for i := 0; i < n; i++ {
+		f.Add(fmt.Sprintf("member:%d", i))
+	}
+
+	const probes = 100_000
+	falsePositives := 0
+	for i := 0; i < probes; i++ {
+		if f.Test(fmt.Sprintf("stranger:%d", i)) {
+			falsePositives++
+		}
+	}
+	measured := float64(falsePositives) / probes
+	if measured > 1.5*target {
+		t.Fatalf("false-positive rate %.4f at capacity, target %.2f", measured, target)
+	}
+	if estimate := f.FalsePositiveRate(); estimate > 1.5*target || estimate < target/1.5 {
+		t.Fatalf("FalsePositiveRate() = %.4f, measured %.4f, target %.2f", estimate, measured, target)
+	}
+}
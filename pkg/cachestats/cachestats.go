@@ -0,0 +1,95 @@
+package cachestats
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Shared hit/miss/eviction accounting for the cache implementations in this repo.
+// Counters are plain atomics so recording a stat never takes the cache's lock.
+
+// Stats is a point-in-time snapshot of a cache's counters
+type Stats struct {
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	Sets        uint64  `json:"sets"`
+	Deletes     uint64  `json:"deletes"`
+	Evictions   uint64  `json:"evictions"`   // Entries removed to make room for new ones
+	Expirations uint64  `json:"expirations"` // Entries removed because their TTL passed
+	Size        int     `json:"size"`        // Number of entries currently stored
+	Bytes       int64   `json:"bytes"`       // Bytes used by the entries, for caches with a byte budget
+	HitRatio    float64 `json:"hit_ratio"`
+}
+
+// String formats the snapshot on a single line
+func (s Stats) String() string {
+	str := fmt.Sprintf("hits=%d misses=%d sets=%d deletes=%d evictions=%d expirations=%d size=%d",
+		s.Hits, s.Misses, s.Sets, s.Deletes, s.Evictions, s.Expirations, s.Size)
+	if s.Bytes > 0 {
+		str += fmt.Sprintf(" bytes=%d", s.Bytes)
+	}
+	return str + fmt.Sprintf(" hit_ratio=%.2f", s.HitRatio)
+}
+
+// Counters holds the live counters of a cache. The zero value is ready to use.
+type Counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	sets        atomic.Uint64
+	deletes     atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+}
+
+func (c *Counters) Hit()    { c.hits.Add(1) }
+func (c *Counters) Miss()   { c.misses.Add(1) }
+func (c *Counters) Set()    { c.sets.Add(1) }
+func (c *Counters) Delete() { c.deletes.Add(1) }
+func (c *Counters) Evict()  { c.evictions.Add(1) }
+func (c *Counters) Expire() { c.expirations.Add(1) }
+
+// Reset sets every counter back to zero
+func (c *Counters) Reset() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.sets.Store(0)
+	c.deletes.Store(0)
+	c.evictions.Store(0)
+	c.expirations.Store(0)
+}
+
+// Snapshot returns the current counters together with the cache's size
+func (c *Counters) Snapshot(size int) Stats {
+	s := Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Sets:        c.sets.Load(),
+		Deletes:     c.deletes.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Size:        size,
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+// Merge adds up snapshots from several caches (e.g. shards) into one
+func Merge(stats ...Stats) Stats {
+	var total Stats
+	for _, s := range stats {
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		total.Sets += s.Sets
+		total.Deletes += s.Deletes
+		total.Evictions += s.Evictions
+		total.Expirations += s.Expirations
+		total.Size += s.Size
+		total.Bytes += s.Bytes
+	}
+	if lookups := total.Hits + total.Misses; lookups > 0 {
+		total.HitRatio = float64(total.Hits) / float64(lookups)
+	}
+	return total
+}
@@ -0,0 +1,72 @@
+package cachestats
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCountersSnapshotAndReset(t *testing.T) {
+	var c Counters
+	for i := 0; i < 3; i++ {
+		c.Hit()
+	}
+	c.Miss()
+	c.Set()
+	c.Set()
+	c.Delete()
+	c.Evict()
+	c.Expire()
+
+	want := Stats{Hits: 3, Misses: 1, Sets: 2, Deletes: 1, Evictions: 1, Expirations: 1, Size: 7, HitRatio: 0.75}
+	if got := c.Snapshot(7); got != want {
+		t.Fatalf("Snapshot(7) = %+v, want %+v", got, want)
+	}
+
+	c.Reset()
+	if got := c.Snapshot(7); got != (Stats{Size: 7}) {
+		t.Fatalf("after Reset: %+v, want only the size", got)
+	}
+}
+
+func TestCountersConcurrentUse(t *testing.T) {
+	var c Counters
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Hit()
+				c.Miss()
+			}
+		}()
+	}
+	wg.Wait()
+	if s := c.Snapshot(0); s.Hits != 8000 || s.Misses != 8000 || s.HitRatio != 0.5 {
+		t.Fatalf("%+v, want 8000 hits and misses", s)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	total := Merge(
+		Stats{Hits: 3, Misses: 1, Sets: 4, Size: 2, Bytes: 100, Evictions: 1},
+		Stats{Hits: 1, Misses: 3, Deletes: 2, Size: 5, Bytes: 50, Expirations: 2},
+	)
+	want := Stats{Hits: 4, Misses: 4, Sets: 4, Deletes: 2, Evictions: 1, Expirations: 2, Size: 7, Bytes: 150, HitRatio: 0.5}
+	if total != want {
+		t.Fatalf("Merge = %+v, want %+v", total, want)
+	}
+	if empty := Merge(); empty != (Stats{}) {
+		t.Fatalf("Merge() = %+v, want zero stats without a hit ratio", empty)
+	}
+}
+
+func TestStringShowsBytesOnlyWhenTracked(t *testing.T) {
+	if s := (Stats{Hits: 1}).String(); strings.Contains(s, "bytes=") || !strings.Contains(s, "hits=1") {
+		t.Fatalf("String() = %q", s)
+	}
+	if s := (Stats{Bytes: 42}).String(); !strings.Contains(s, "bytes=42") {
+		t.Fatalf("String() = %q, want the byte count", s)
+	}
+}
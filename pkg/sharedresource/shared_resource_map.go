@@ -2,7 +2,9 @@ package sharedresource
 
 import (
 	"fmt"
+	"go-ex/pkg/cachestats"
 	"sync"
+	"sync/atomic"
 )
 
 // sync.Map for concurrent map access
@@ -10,22 +12,54 @@ import (
 
 // ConcurrentCache is a simple concurrent-safe cache using sync.Map
 type ConcurrentCache struct {
-	data sync.Map // sync.Map is safe for concurrent use
+	data  sync.Map     // sync.Map is safe for concurrent use
+	size  atomic.Int64 // sync.Map has no len(), so the entry count is tracked separately
+	stats cachestats.Counters
+	Quiet bool // Don't print every cache operation (they are printed by default)
 }
 
 // Store adds or updates a key-value pair in the cache
 func (c *ConcurrentCache) Store(key string, value interface{}) {
-	c.data.Store(key, value)
-	fmt.Printf("Stored key: %s\n", key)
+	if _, loaded := c.data.Swap(key, value); !loaded {
+		c.size.Add(1) // Only new keys grow the cache
+	}
+	c.stats.Set()
+	c.logf("Stored key: %s\n", key)
 }
 
 // Load retrieves a value from the cache
 func (c *ConcurrentCache) Load(key string) (interface{}, bool) {
 	value, ok := c.data.Load(key)
-	fmt.Printf("Loaded key: %s, found: %t\n", key, ok)
+	if ok {
+		c.stats.Hit()
+	} else {
+		c.stats.Miss()
+	}
+	c.logf("Loaded key: %s, found: %t\n", key, ok)
 	return value, ok
 }
 
+// Delete removes a key-value pair from the cache
+func (c *ConcurrentCache) Delete(key string) {
+	if _, loaded := c.data.LoadAndDelete(key); loaded {
+		c.size.Add(-1)
+		c.stats.Delete()
+	}
+	c.logf("Deleted key: %s\n", key)
+}
+
+// Stats returns a snapshot of the cache's hit/miss counters and current size
+func (c *ConcurrentCache) Stats() cachestats.Stats {
+	return c.stats.Snapshot(int(c.size.Load()))
+}
+
+// logf prints a cache operation unless Quiet is set
+func (c *ConcurrentCache) logf(format string, args ...interface{}) {
+	if !c.Quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
 func RunSharedResourceMap() {
 	cache := ConcurrentCache{}
 	var wg sync.WaitGroup
@@ -55,4 +89,5 @@ func RunSharedResourceMap() {
 
 	wg.Wait()
 	fmt.Println("Finished loading data.")
+	fmt.Printf("Stats: %s\n", cache.Stats())
 }